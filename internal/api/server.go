@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -46,7 +47,7 @@ func NewServer(cfg *config.Config, scraperService *scraper.Service, logger *logg
 	wsManager := NewWebSocketManager(logger)
 
 	// Initialize Scheduler
-	scheduler := scheduler.NewScheduler(logger, scraperService)
+	scheduler := scheduler.NewScheduler(logger, scraperService, newJobStore(cfg, logger))
 
 	server := &Server{
 		router:         router,
@@ -63,6 +64,10 @@ func NewServer(cfg *config.Config, scraperService *scraper.Service, logger *logg
 		server.onScheduledJobComplete,
 		server.onScheduledJobError,
 	)
+	scheduler.SetChangeCallback(server.onScheduledJobChanged)
+	scheduler.SetPauseCallback(server.onScheduledJobPaused)
+	scheduler.SetMaxConcurrentJobs(cfg.Scheduler.MaxConcurrentJobs)
+	scheduler.SetWebhookURL(cfg.Scheduler.WebhookURL)
 
 	server.setupRoutes()
 
@@ -88,6 +93,13 @@ func (s *Server) setupRoutes() {
 		api.GET("/scrape/stats", s.getWebsiteStats)
 		api.POST("/scrape/stats/advanced", s.getWebsiteStatsAdvanced)
 
+		// Crawling Routes
+		api.POST("/crawl", s.crawlWebsite)
+		api.POST("/sitemap", s.getSitemap)
+		api.GET("/proxies", s.getProxyStatus)
+		api.DELETE("/sessions/:name", s.deleteSession)
+		api.DELETE("/cache", s.purgeCache)
+
 		// Export Routes
 		api.GET("/export/csv", s.exportToCSV)
 		api.GET("/export/json", s.exportToJSON)
@@ -103,6 +115,8 @@ func (s *Server) setupRoutes() {
 		api.POST("/scheduler/jobs/:id/pause", s.pauseScheduledJob)
 		api.POST("/scheduler/jobs/:id/resume", s.resumeScheduledJob)
 		api.POST("/scheduler/jobs/:id/run", s.runScheduledJobNow)
+		api.POST("/scheduler/jobs/:id/cancel", s.cancelScheduledJob)
+		api.GET("/scheduler/jobs/:id/changes", s.getScheduledJobChanges)
 		api.GET("/scheduler/stats", s.getSchedulerStats)
 		api.GET("/scheduler/export", s.exportScheduledJobs)
 		api.POST("/scheduler/import", s.importScheduledJobs)
@@ -147,9 +161,7 @@ func (s *Server) scrapeWebsite(c *gin.Context) {
 	if err != nil {
 		s.logger.Errorf("Scraping error: %v", err)
 		s.wsManager.BroadcastError(request.URL, err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		c.JSON(scrapeErrorResponse(err))
 		return
 	}
 
@@ -190,11 +202,17 @@ func (s *Server) scrapeWebsiteAdvanced(c *gin.Context) {
 			ExtractStyles:    false,
 			ExtractHeaders:   false,
 			UserAgent:        "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
-			FollowRedirects:  true,
 			RespectRobotsTxt: false,
 		}
 	}
 
+	if err := request.Options.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid options: " + err.Error(),
+		})
+		return
+	}
+
 	// Broadcast scraping start
 	s.wsManager.BroadcastScrapingUpdate(request.URL, "started", nil)
 
@@ -205,9 +223,7 @@ func (s *Server) scrapeWebsiteAdvanced(c *gin.Context) {
 	if err != nil {
 		s.logger.Errorf("Advanced scraping error: %v", err)
 		s.wsManager.BroadcastError(request.URL, err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		c.JSON(scrapeErrorResponse(err))
 		return
 	}
 
@@ -246,8 +262,7 @@ func (s *Server) scrapeMultipleWebsites(c *gin.Context) {
 	results := make([]*scraper.ScrapedData, 0, len(request.URLs))
 	completed := 0
 
-	// Semaphore for concurrency control
-	semaphore := make(chan struct{}, 5) // Max 5 concurrent requests
+	// Concurrency and per-host rate limits are enforced by the scraper service
 	resultsChan := make(chan *scraper.ScrapedData, len(request.URLs))
 	errorsChan := make(chan error, len(request.URLs))
 
@@ -255,11 +270,7 @@ func (s *Server) scrapeMultipleWebsites(c *gin.Context) {
 	s.wsManager.BroadcastBatchProgress(len(request.URLs), 0, "Starting batch scraping...")
 
 	for _, url := range request.URLs {
-		semaphore <- struct{}{} // Acquire semaphore
-
 		go func(u string) {
-			defer func() { <-semaphore }() // Release semaphore
-
 			// Broadcast individual scraping start
 			s.wsManager.BroadcastScrapingUpdate(u, "started", nil)
 
@@ -333,19 +344,24 @@ func (s *Server) scrapeMultipleWebsitesAdvanced(c *gin.Context) {
 			ExtractStyles:    false,
 			ExtractHeaders:   false,
 			UserAgent:        "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
-			FollowRedirects:  true,
 			RespectRobotsTxt: false,
 		}
 	}
 
+	if err := request.Options.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid options: " + err.Error(),
+		})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(c.Request.Context(), request.Options.Timeout)
 	defer cancel()
 
 	results := make([]*scraper.ScrapedData, 0, len(request.URLs))
 	completed := 0
 
-	// Semaphore for concurrency control
-	semaphore := make(chan struct{}, 5) // Max 5 concurrent requests
+	// Concurrency and per-host rate limits are enforced by the scraper service
 	resultsChan := make(chan *scraper.ScrapedData, len(request.URLs))
 	errorsChan := make(chan error, len(request.URLs))
 
@@ -353,16 +369,7 @@ func (s *Server) scrapeMultipleWebsitesAdvanced(c *gin.Context) {
 	s.wsManager.BroadcastBatchProgress(len(request.URLs), 0, "Starting advanced batch scraping...")
 
 	for _, url := range request.URLs {
-		semaphore <- struct{}{} // Acquire semaphore
-
 		go func(u string) {
-			defer func() { <-semaphore }() // Release semaphore
-
-			// Add delay if specified
-			if request.Options.Delay > 0 {
-				time.Sleep(request.Options.Delay)
-			}
-
 			// Broadcast individual scraping start
 			s.wsManager.BroadcastScrapingUpdate(u, "started", nil)
 
@@ -402,6 +409,134 @@ func (s *Server) scrapeMultipleWebsitesAdvanced(c *gin.Context) {
 	})
 }
 
+func (s *Server) crawlWebsite(c *gin.Context) {
+	var request struct {
+		URL     string                   `json:"url" binding:"required"`
+		Options *scraper.CrawlingOptions `json:"options"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "URL is required",
+		})
+		return
+	}
+
+	// Use default options if none provided
+	if request.Options == nil {
+		request.Options = &scraper.CrawlingOptions{
+			MaxDepth:         2,
+			MaxPages:         20,
+			Timeout:          30 * time.Second,
+			Delay:            0,
+			ExtractImages:    true,
+			ExtractLinks:     true,
+			ExtractForms:     false,
+			ExtractTables:    false,
+			ExtractScripts:   false,
+			ExtractStyles:    false,
+			ExtractHeaders:   false,
+			UserAgent:        "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
+			RespectRobotsTxt: false,
+		}
+	}
+
+	if err := request.Options.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid options: " + err.Error(),
+		})
+		return
+	}
+
+	if request.Options.MaxPages > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Maximum 500 pages allowed per crawl",
+		})
+		return
+	}
+
+	// Broadcast crawl start
+	s.wsManager.BroadcastScrapingUpdate(request.URL, "started", nil)
+
+	// Timeout applies per page, the crawl itself is bound to the request
+	result, err := s.scraperService.Crawl(c.Request.Context(), request.URL, request.Options)
+	if err != nil {
+		s.logger.Errorf("Crawling error: %v", err)
+		s.wsManager.BroadcastError(request.URL, err.Error())
+		if status, body := scrapeErrorResponse(err); status == http.StatusForbidden {
+			c.JSON(status, body)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Broadcast crawl completion
+	s.wsManager.BroadcastScrapingUpdate(request.URL, "completed", gin.H{
+		"page_count":  result.PageCount,
+		"error_count": result.ErrorCount,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+		"count":   result.PageCount,
+		"options": request.Options,
+	})
+}
+
+func (s *Server) getSitemap(c *gin.Context) {
+	var request struct {
+		URL     string                   `json:"url" binding:"required"`
+		Options *scraper.CrawlingOptions `json:"options"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "URL is required",
+		})
+		return
+	}
+
+	// Use default options if none provided
+	if request.Options == nil {
+		request.Options = &scraper.CrawlingOptions{
+			Timeout:          30 * time.Second,
+			UserAgent:        "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
+			RespectRobotsTxt: false,
+		}
+	}
+
+	if err := request.Options.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid options: " + err.Error(),
+		})
+		return
+	}
+
+	result, err := s.scraperService.Sitemap(c.Request.Context(), request.URL, request.Options)
+	if err != nil {
+		s.logger.Errorf("Sitemap error: %v", err)
+		if status, body := scrapeErrorResponse(err); status == http.StatusForbidden {
+			c.JSON(status, body)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+		"count":   result.Count,
+		"options": request.Options,
+	})
+}
+
 func (s *Server) getWebsiteStats(c *gin.Context) {
 	url := c.Query("url")
 	if url == "" {
@@ -417,9 +552,7 @@ func (s *Server) getWebsiteStats(c *gin.Context) {
 	stats, err := s.scraperService.GetWebsiteStats(ctx, url)
 	if err != nil {
 		s.logger.Errorf("Stats error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		c.JSON(scrapeErrorResponse(err))
 		return
 	}
 
@@ -457,20 +590,24 @@ func (s *Server) getWebsiteStatsAdvanced(c *gin.Context) {
 			ExtractStyles:    true,
 			ExtractHeaders:   true,
 			UserAgent:        "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
-			FollowRedirects:  true,
 			RespectRobotsTxt: false,
 		}
 	}
 
+	if err := request.Options.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid options: " + err.Error(),
+		})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(c.Request.Context(), request.Options.Timeout)
 	defer cancel()
 
 	stats, err := s.scraperService.GetWebsiteStatsWithOptions(ctx, request.URL, request.Options)
 	if err != nil {
 		s.logger.Errorf("Advanced stats error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		c.JSON(scrapeErrorResponse(err))
 		return
 	}
 
@@ -496,9 +633,7 @@ func (s *Server) exportToCSV(c *gin.Context) {
 	data, err := s.scraperService.ScrapeWebsite(ctx, url)
 	if err != nil {
 		s.logger.Errorf("Export error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		c.JSON(scrapeErrorResponse(err))
 		return
 	}
 
@@ -524,9 +659,7 @@ func (s *Server) exportToJSON(c *gin.Context) {
 	data, err := s.scraperService.ScrapeWebsite(ctx, url)
 	if err != nil {
 		s.logger.Errorf("Export error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		c.JSON(scrapeErrorResponse(err))
 		return
 	}
 
@@ -563,20 +696,24 @@ func (s *Server) exportToCSVAdvanced(c *gin.Context) {
 			ExtractStyles:    true,
 			ExtractHeaders:   true,
 			UserAgent:        "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
-			FollowRedirects:  true,
 			RespectRobotsTxt: false,
 		}
 	}
 
+	if err := request.Options.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid options: " + err.Error(),
+		})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(c.Request.Context(), request.Options.Timeout)
 	defer cancel()
 
 	data, err := s.scraperService.ScrapeWebsiteWithOptions(ctx, request.URL, request.Options)
 	if err != nil {
 		s.logger.Errorf("Advanced export error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		c.JSON(scrapeErrorResponse(err))
 		return
 	}
 
@@ -615,20 +752,24 @@ func (s *Server) exportToJSONAdvanced(c *gin.Context) {
 			ExtractStyles:    true,
 			ExtractHeaders:   true,
 			UserAgent:        "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
-			FollowRedirects:  true,
 			RespectRobotsTxt: false,
 		}
 	}
 
+	if err := request.Options.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid options: " + err.Error(),
+		})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(c.Request.Context(), request.Options.Timeout)
 	defer cancel()
 
 	data, err := s.scraperService.ScrapeWebsiteWithOptions(ctx, request.URL, request.Options)
 	if err != nil {
 		s.logger.Errorf("Advanced export error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		c.JSON(scrapeErrorResponse(err))
 		return
 	}
 
@@ -715,10 +856,62 @@ func (s *Server) Start() error {
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
+	var err error
 	if s.server != nil {
-		return s.server.Shutdown(ctx)
+		err = s.server.Shutdown(ctx)
+	}
+	// Cancel running scheduled jobs and give them until ctx is done to
+	// record their results
+	if stopErr := s.scheduler.Stop(ctx); stopErr != nil {
+		s.logger.Warnf("Scheduler did not stop cleanly: %v", stopErr)
+	}
+	return err
+}
+
+// newJobStore opens the configured job store. Jobs are kept in memory when
+// no store file is configured or it cannot be opened.
+func newJobStore(cfg *config.Config, logger *logger.Logger) scheduler.JobStore {
+	if cfg.Scheduler.StoreFile == "" {
+		return scheduler.NewMemoryJobStore()
+	}
+	store, err := scheduler.NewFileJobStore(cfg.Scheduler.StoreFile)
+	if err != nil {
+		logger.Errorf("Failed to open job store, scheduled jobs will not be persisted: %v", err)
+		return scheduler.NewMemoryJobStore()
+	}
+	return store
+}
+
+// scrapeErrorResponse builds the error response for a failed scrape, adding
+// the error type and attempt count when the scraper provides them. URLs
+// blocked by the URL safety policy get 403 with the block reason.
+func scrapeErrorResponse(err error) (int, gin.H) {
+	body := gin.H{
+		"error": err.Error(),
+	}
+
+	var scrapeErr *scraper.ScrapeError
+	if errors.As(err, &scrapeErr) {
+		body["error_type"] = scrapeErr.Kind
+		body["attempts"] = scrapeErr.Attempts
+		if scrapeErr.StatusCode != 0 {
+			body["status_code"] = scrapeErr.StatusCode
+		}
+	}
+
+	// Secrets referenced for hosts they are not bound to
+	if errors.Is(err, scraper.ErrSecretHostNotAllowed) {
+		return http.StatusBadRequest, body
 	}
-	return nil
+
+	// URLs refused by the URL safety policy
+	var blocked *scraper.BlockedURLError
+	if errors.As(err, &blocked) {
+		body["reason"] = blocked.Reason
+		return http.StatusForbidden, body
+	}
+
+	return http.StatusInternalServerError, body
 }
 
 func corsMiddleware() gin.HandlerFunc {
@@ -749,6 +942,14 @@ func (s *Server) onScheduledJobError(jobResult *scheduler.JobResult) {
 	s.wsManager.BroadcastScheduledJobError(jobResult)
 }
 
+func (s *Server) onScheduledJobChanged(jobResult *scheduler.JobResult) {
+	s.wsManager.BroadcastScheduledJobChanged(jobResult)
+}
+
+func (s *Server) onScheduledJobPaused(jobResult *scheduler.JobResult) {
+	s.wsManager.BroadcastScheduledJobPaused(jobResult)
+}
+
 // Scheduled Jobs API endpoints
 func (s *Server) getScheduledJobs(c *gin.Context) {
 	jobs := s.scheduler.GetAllJobs()
@@ -761,11 +962,18 @@ func (s *Server) getScheduledJobs(c *gin.Context) {
 
 func (s *Server) createScheduledJob(c *gin.Context) {
 	var request struct {
-		Name        string                   `json:"name" binding:"required"`
-		Description string                   `json:"description"`
-		Schedule    string                   `json:"schedule" binding:"required"`
-		URL         string                   `json:"url" binding:"required"`
-		Options     *scraper.CrawlingOptions `json:"options"`
+		Name            string                     `json:"name" binding:"required"`
+		Description     string                     `json:"description"`
+		Schedule        string                     `json:"schedule" binding:"required"`
+		URL             string                     `json:"url" binding:"required"`
+		Options         *scraper.CrawlingOptions   `json:"options"`
+		ChangeDetection *scheduler.ChangeDetection `json:"change_detection"`
+		TimeZone        string                     `json:"time_zone"`
+		BlackoutWindows []scheduler.BlackoutWindow `json:"blackout_windows"`
+		BlackoutPolicy  scheduler.BlackoutPolicy   `json:"blackout_policy"`
+		OverlapPolicy   scheduler.OverlapPolicy    `json:"overlap_policy"`
+		Retry           *scheduler.RetryPolicy     `json:"retry"`
+		PauseAfter      int                        `json:"pause_after_failures"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -775,6 +983,31 @@ func (s *Server) createScheduledJob(c *gin.Context) {
 		return
 	}
 
+	if err := scheduler.ValidateSchedule(request.Schedule, request.TimeZone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := scheduler.ValidateBlackouts(request.BlackoutWindows, request.BlackoutPolicy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := scheduler.ValidateOverlapPolicy(request.OverlapPolicy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := scheduler.ValidateRetry(request.Retry, request.PauseAfter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
 	// Use default options if none provided
 	if request.Options == nil {
 		request.Options = &scraper.CrawlingOptions{
@@ -790,17 +1023,39 @@ func (s *Server) createScheduledJob(c *gin.Context) {
 			ExtractStyles:    false,
 			ExtractHeaders:   false,
 			UserAgent:        "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
-			FollowRedirects:  true,
 			RespectRobotsTxt: false,
 		}
 	}
 
+	if err := request.Options.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid options: " + err.Error(),
+		})
+		return
+	}
+
+	if request.ChangeDetection != nil {
+		if err := request.ChangeDetection.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid change detection: " + err.Error(),
+			})
+			return
+		}
+	}
+
 	job := &scheduler.ScheduledJob{
-		Name:        request.Name,
-		Description: request.Description,
-		Schedule:    request.Schedule,
-		URL:         request.URL,
-		Options:     request.Options,
+		Name:               request.Name,
+		Description:        request.Description,
+		Schedule:           request.Schedule,
+		URL:                request.URL,
+		Options:            request.Options,
+		ChangeDetection:    request.ChangeDetection,
+		TimeZone:           request.TimeZone,
+		BlackoutWindows:    request.BlackoutWindows,
+		BlackoutPolicy:     request.BlackoutPolicy,
+		OverlapPolicy:      request.OverlapPolicy,
+		Retry:              request.Retry,
+		PauseAfterFailures: request.PauseAfter,
 	}
 
 	if err := s.scheduler.AddJob(job); err != nil {
@@ -838,7 +1093,8 @@ func (s *Server) getScheduledJob(c *gin.Context) {
 
 func (s *Server) updateScheduledJob(c *gin.Context) {
 	jobID := c.Param("id")
-	job, err := s.scheduler.GetJob(jobID)
+	// Changes are made to a copy that the scheduler applies under its lock
+	job, err := s.scheduler.GetJobCopy(jobID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "Job not found",
@@ -847,11 +1103,18 @@ func (s *Server) updateScheduledJob(c *gin.Context) {
 	}
 
 	var request struct {
-		Name        string                   `json:"name"`
-		Description string                   `json:"description"`
-		Schedule    string                   `json:"schedule"`
-		URL         string                   `json:"url"`
-		Options     *scraper.CrawlingOptions `json:"options"`
+		Name            string                     `json:"name"`
+		Description     string                     `json:"description"`
+		Schedule        string                     `json:"schedule"`
+		URL             string                     `json:"url"`
+		Options         *scraper.CrawlingOptions   `json:"options"`
+		ChangeDetection *scheduler.ChangeDetection `json:"change_detection"`
+		TimeZone        *string                    `json:"time_zone"`
+		BlackoutWindows []scheduler.BlackoutWindow `json:"blackout_windows"`
+		BlackoutPolicy  scheduler.BlackoutPolicy   `json:"blackout_policy"`
+		OverlapPolicy   scheduler.OverlapPolicy    `json:"overlap_policy"`
+		Retry           *scheduler.RetryPolicy     `json:"retry"`
+		PauseAfter      *int                       `json:"pause_after_failures"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -861,6 +1124,68 @@ func (s *Server) updateScheduledJob(c *gin.Context) {
 		return
 	}
 
+	schedule, timeZone := job.Schedule, job.TimeZone
+	if request.Schedule != "" {
+		schedule = request.Schedule
+	}
+	if request.TimeZone != nil {
+		timeZone = *request.TimeZone
+	}
+	if err := scheduler.ValidateSchedule(schedule, timeZone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	blackoutWindows, blackoutPolicy := job.BlackoutWindows, job.BlackoutPolicy
+	if request.BlackoutWindows != nil {
+		blackoutWindows = request.BlackoutWindows
+	}
+	if request.BlackoutPolicy != "" {
+		blackoutPolicy = request.BlackoutPolicy
+	}
+	if err := scheduler.ValidateBlackouts(blackoutWindows, blackoutPolicy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := scheduler.ValidateOverlapPolicy(request.OverlapPolicy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	retry, pauseAfter := job.Retry, job.PauseAfterFailures
+	if request.Retry != nil {
+		retry = request.Retry
+	}
+	if request.PauseAfter != nil {
+		pauseAfter = *request.PauseAfter
+	}
+	if err := scheduler.ValidateRetry(retry, pauseAfter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if request.Options != nil {
+		if err := request.Options.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid options: " + err.Error(),
+			})
+			return
+		}
+	}
+	if request.ChangeDetection != nil {
+		if err := request.ChangeDetection.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid change detection: " + err.Error(),
+			})
+			return
+		}
+	}
+
 	// Update fields if provided
 	if request.Name != "" {
 		job.Name = request.Name
@@ -877,23 +1202,28 @@ func (s *Server) updateScheduledJob(c *gin.Context) {
 	if request.Options != nil {
 		job.Options = request.Options
 	}
-
-	job.UpdatedAt = time.Now()
-
-	// Remove and re-add job to update schedule
-	if err := s.scheduler.RemoveJob(jobID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to update job",
-		})
-		return
+	if request.ChangeDetection != nil {
+		job.ChangeDetection = request.ChangeDetection
+	}
+	job.TimeZone = timeZone
+	job.BlackoutWindows = blackoutWindows
+	job.BlackoutPolicy = blackoutPolicy
+	if request.OverlapPolicy != "" {
+		job.OverlapPolicy = request.OverlapPolicy
 	}
+	job.Retry = retry
+	job.PauseAfterFailures = pauseAfter
 
-	if err := s.scheduler.AddJob(job); err != nil {
+	// Reschedule and persist the job
+	if err := s.scheduler.UpdateJob(job); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to update job schedule",
 		})
 		return
 	}
+	if updated, err := s.scheduler.GetJobCopy(jobID); err == nil {
+		job = updated
+	}
 
 	// Broadcast job list update
 	s.wsManager.BroadcastScheduledJobList(s.scheduler.GetAllJobs())
@@ -961,7 +1291,11 @@ func (s *Server) resumeScheduledJob(c *gin.Context) {
 func (s *Server) runScheduledJobNow(c *gin.Context) {
 	jobID := c.Param("id")
 	if err := s.scheduler.RunJobNow(jobID); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
+		status := http.StatusNotFound
+		if errors.Is(err, scheduler.ErrRunInProgress) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
 			"error": err.Error(),
 		})
 		return
@@ -973,6 +1307,75 @@ func (s *Server) runScheduledJobNow(c *gin.Context) {
 	})
 }
 
+func (s *Server) getProxyStatus(c *gin.Context) {
+	proxies := s.scraperService.ProxyPoolStatus()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    proxies,
+		"count":   len(proxies),
+	})
+}
+
+func (s *Server) deleteSession(c *gin.Context) {
+	name := c.Param("name")
+	if err := s.scraperService.ClearSession(name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Session deleted successfully",
+	})
+}
+
+func (s *Server) purgeCache(c *gin.Context) {
+	s.scraperService.PurgeCache()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Cache purged successfully",
+	})
+}
+
+func (s *Server) cancelScheduledJob(c *gin.Context) {
+	jobID := c.Param("id")
+	if err := s.scheduler.CancelJob(jobID); err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, scheduler.ErrJobNotRunning) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Job cancelled successfully",
+	})
+}
+
+func (s *Server) getScheduledJobChanges(c *gin.Context) {
+	jobID := c.Param("id")
+	changes, err := s.scheduler.GetJobChanges(jobID, c.Query("changed") == "true")
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Job not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    changes,
+		"count":   len(changes),
+	})
+}
+
 func (s *Server) getSchedulerStats(c *gin.Context) {
 	stats := s.scheduler.GetJobStats()
 	c.JSON(http.StatusOK, gin.H{
@@ -55,6 +55,21 @@ type ScheduledJobUpdate struct {
 	Error     string `json:"error,omitempty"`
 }
 
+type ScheduledJobChange struct {
+	JobID   string               `json:"job_id"`
+	JobName string               `json:"job_name"`
+	URL     string               `json:"url"`
+	Score   float64              `json:"score"`
+	Changes *scheduler.ChangeSet `json:"changes"`
+}
+
+type ScheduledJobPaused struct {
+	JobID   string `json:"job_id"`
+	JobName string `json:"job_name"`
+	Reason  string `json:"reason"`
+	Error   string `json:"error,omitempty"`
+}
+
 func NewWebSocketManager(logger *logger.Logger) *WebSocketManager {
 	return &WebSocketManager{
 		clients:    make(map[*websocket.Conn]bool),
@@ -252,6 +267,35 @@ func (w *WebSocketManager) BroadcastScheduledJobError(jobResult *scheduler.JobRe
 	w.broadcast <- msg
 }
 
+func (w *WebSocketManager) BroadcastScheduledJobChanged(jobResult *scheduler.JobResult) {
+	msg := WebSocketMessage{
+		Type: "scheduled_job_changed",
+		Data: ScheduledJobChange{
+			JobID:   jobResult.JobID,
+			JobName: jobResult.JobName,
+			URL:     jobResult.Data.URL,
+			Score:   jobResult.Changes.Score,
+			Changes: jobResult.Changes,
+		},
+		Time: time.Now(),
+	}
+	w.broadcast <- msg
+}
+
+func (w *WebSocketManager) BroadcastScheduledJobPaused(jobResult *scheduler.JobResult) {
+	msg := WebSocketMessage{
+		Type: "scheduled_job_paused",
+		Data: ScheduledJobPaused{
+			JobID:   jobResult.JobID,
+			JobName: jobResult.JobName,
+			Reason:  jobResult.Reason,
+			Error:   jobResult.Error,
+		},
+		Time: time.Now(),
+	}
+	w.broadcast <- msg
+}
+
 func (w *WebSocketManager) BroadcastScheduledJobList(jobs []*scheduler.ScheduledJob) {
 	msg := WebSocketMessage{
 		Type: "scheduled_jobs_list",
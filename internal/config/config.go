@@ -4,14 +4,66 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Port     int    `mapstructure:"PORT"`
-	LogLevel string `mapstructure:"LOG_LEVEL"`
-	Timeout  int    `mapstructure:"TIMEOUT"`
+	Port      int             `mapstructure:"PORT"`
+	LogLevel  string          `mapstructure:"LOG_LEVEL"`
+	Timeout   int             `mapstructure:"TIMEOUT"`
+	Scraping  ScrapingConfig  `mapstructure:"SCRAPING"`
+	Scheduler SchedulerConfig `mapstructure:"SCHEDULER"`
+}
+
+type SchedulerConfig struct {
+	// Database file for scheduled jobs and their history, including inline
+	// credentials, empty keeps jobs in memory only
+	StoreFile string `mapstructure:"STORE_FILE"`
+	// Runs in progress at once across all jobs, 0 for no limit
+	MaxConcurrentJobs int `mapstructure:"MAX_CONCURRENT_JOBS"`
+	// URL notified when a job is paused after repeated failures, empty to
+	// disable
+	WebhookURL string `mapstructure:"WEBHOOK_URL"`
+}
+
+type ScrapingConfig struct {
+	MaxConcurrent   int    `mapstructure:"MAX_CONCURRENT"`
+	UserAgent       string `mapstructure:"USER_AGENT"`
+	FollowRedirects bool   `mapstructure:"FOLLOW_REDIRECTS"`
+	MaxRedirects    int    `mapstructure:"MAX_REDIRECTS"`
+	// Per-host politeness
+	MaxPerHost    int     `mapstructure:"MAX_PER_HOST"`
+	HostRateLimit float64 `mapstructure:"HOST_RATE_LIMIT"`
+	HostBurst     int     `mapstructure:"HOST_BURST"`
+	// Response limits; sizes like "10MB", text length in characters
+	MaxBodySize         string `mapstructure:"MAX_BODY_SIZE"`
+	MaxDecompressedSize string `mapstructure:"MAX_DECOMPRESSED_SIZE"`
+	MaxTextLength       int    `mapstructure:"MAX_TEXT_LENGTH"`
+	// URL safety policy; host patterns like "example.com" or "*.example.com"
+	AllowedSchemes       []string `mapstructure:"ALLOWED_SCHEMES"`
+	AllowPrivateNetworks bool     `mapstructure:"ALLOW_PRIVATE_NETWORKS"`
+	AllowedHosts         []string `mapstructure:"ALLOWED_HOSTS"`
+	DeniedHosts          []string `mapstructure:"DENIED_HOSTS"`
+	// Proxy pool used when a request does not set its own proxy
+	Proxies                  []string `mapstructure:"PROXIES"`
+	ProxyRotation            string   `mapstructure:"PROXY_ROTATION"`
+	ProxyMaxFailures         int      `mapstructure:"PROXY_MAX_FAILURES"`
+	ProxyHealthCheckURL      string   `mapstructure:"PROXY_HEALTH_CHECK_URL"`
+	ProxyHealthCheckInterval int      `mapstructure:"PROXY_HEALTH_CHECK_INTERVAL"`
+	// Directory for named cookie sessions, empty keeps them in memory only
+	SessionDir string `mapstructure:"SESSION_DIR"`
+	// Directory with one file per secret, in addition to the
+	// SCRAPER_SECRET_<NAME> environment variables. A secret is only sent to
+	// the hosts listed in <name>.hosts or SCRAPER_SECRET_<NAME>_HOSTS
+	SecretsDir string `mapstructure:"SECRETS_DIR"`
+	// Response cache for conditional requests; without a directory the
+	// cache is kept in memory
+	CacheEnabled bool   `mapstructure:"CACHE_ENABLED"`
+	CacheDir     string `mapstructure:"CACHE_DIR"`
+	CacheTTL     int    `mapstructure:"CACHE_TTL"`
+	CacheMaxSize string `mapstructure:"CACHE_MAX_SIZE"`
 }
 
 func Load() *Config {
@@ -19,6 +71,27 @@ func Load() *Config {
 	viper.SetDefault("PORT", 8080)
 	viper.SetDefault("LOG_LEVEL", "info")
 	viper.SetDefault("TIMEOUT", 30)
+	viper.SetDefault("SCRAPING.MAX_CONCURRENT", 5)
+	viper.SetDefault("SCRAPING.FOLLOW_REDIRECTS", true)
+	viper.SetDefault("SCRAPING.MAX_REDIRECTS", 10)
+	viper.SetDefault("SCRAPING.MAX_PER_HOST", 2)
+	viper.SetDefault("SCRAPING.HOST_RATE_LIMIT", 2.0)
+	viper.SetDefault("SCRAPING.HOST_BURST", 2)
+	viper.SetDefault("SCRAPING.MAX_BODY_SIZE", "10MB")
+	viper.SetDefault("SCRAPING.MAX_DECOMPRESSED_SIZE", "50MB")
+	viper.SetDefault("SCRAPING.MAX_TEXT_LENGTH", 1000000)
+	viper.SetDefault("SCRAPING.ALLOWED_SCHEMES", []string{"http", "https"})
+	viper.SetDefault("SCRAPING.ALLOW_PRIVATE_NETWORKS", false)
+	viper.SetDefault("SCRAPING.CACHE_ENABLED", true)
+	viper.SetDefault("SCRAPING.CACHE_TTL", 86400)
+	viper.SetDefault("SCRAPING.CACHE_MAX_SIZE", "100MB")
+	viper.SetDefault("SCRAPING.PROXY_ROTATION", "round_robin")
+	viper.SetDefault("SCRAPING.PROXY_MAX_FAILURES", 3)
+	viper.SetDefault("SCRAPING.PROXY_HEALTH_CHECK_URL", "https://www.google.com/generate_204")
+	viper.SetDefault("SCRAPING.PROXY_HEALTH_CHECK_INTERVAL", 60)
+	viper.SetDefault("SCHEDULER.STORE_FILE", "data/scheduler.db")
+	viper.SetDefault("SCHEDULER.MAX_CONCURRENT_JOBS", 5)
+	viper.SetDefault("SCHEDULER.WEBHOOK_URL", "")
 
 	// Read environment variables
 	viper.AutomaticEnv()
@@ -48,3 +121,31 @@ func Load() *Config {
 
 	return config
 }
+
+// ParseSize parses a byte size like "512KB", "10MB" or "1GB" using binary
+// units. A plain number is a size in bytes.
+func ParseSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return n * multiplier, nil
+}
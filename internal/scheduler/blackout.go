@@ -0,0 +1,184 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BlackoutPolicy decides what happens to a run that falls into a blackout
+// window.
+type BlackoutPolicy string
+
+const (
+	// BlackoutSkip drops the run, the job runs again at its next scheduled
+	// time
+	BlackoutSkip BlackoutPolicy = "skip"
+	// BlackoutDefer runs the job once the blackout window has ended
+	BlackoutDefer BlackoutPolicy = "defer"
+)
+
+// BlackoutWindow is a period during which a job must not run, in the time
+// zone of the job. Days and dates restrict the window to certain days;
+// start and end restrict it to a time of day and may span midnight, in
+// which case the window belongs to the day it starts on. Without start and
+// end the window covers whole days.
+type BlackoutWindow struct {
+	// Weekdays like "mon" or "saturday", every day when empty
+	Days []string `json:"days,omitempty"`
+	// Specific dates like "2026-12-24", any date when empty
+	Dates []string `json:"dates,omitempty"`
+	// Time of day like "02:00"
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// ValidateBlackouts checks blackout windows and the policy applied to them.
+func ValidateBlackouts(windows []BlackoutWindow, policy BlackoutPolicy) error {
+	switch policy {
+	case "", BlackoutSkip, BlackoutDefer:
+	default:
+		return fmt.Errorf("invalid blackout policy %q", policy)
+	}
+	for i, window := range windows {
+		if err := window.validate(); err != nil {
+			return fmt.Errorf("blackout window %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (w BlackoutWindow) validate() error {
+	if len(w.Days) == 0 && len(w.Dates) == 0 && w.Start == "" && w.End == "" {
+		return fmt.Errorf("days, dates or a time range are required")
+	}
+	for _, day := range w.Days {
+		if _, ok := weekdays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("invalid day %q", day)
+		}
+	}
+	for _, date := range w.Dates {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return fmt.Errorf("invalid date %q", date)
+		}
+	}
+	if (w.Start == "") != (w.End == "") {
+		return fmt.Errorf("start and end must be set together")
+	}
+	if w.Start != "" {
+		start, err := clockSeconds(w.Start)
+		if err != nil {
+			return err
+		}
+		end, err := clockSeconds(w.End)
+		if err != nil {
+			return err
+		}
+		if start == end {
+			return fmt.Errorf("start and end must differ")
+		}
+	}
+	return nil
+}
+
+// clockSeconds parses a time of day into seconds since midnight.
+func clockSeconds(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", value)
+	}
+	return t.Hour()*3600 + t.Minute()*60, nil
+}
+
+// matchesDay reports whether the window applies to the day of t.
+func (w BlackoutWindow) matchesDay(t time.Time) bool {
+	if len(w.Days) > 0 {
+		matched := false
+		for _, day := range w.Days {
+			if weekdays[strings.ToLower(day)] == t.Weekday() {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(w.Dates) > 0 {
+		date := t.Format("2006-01-02")
+		for _, d := range w.Dates {
+			if d == date {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// end returns when the window ends if it contains t, which must be in the
+// time zone of the job.
+func (w BlackoutWindow) end(t time.Time) (time.Time, bool) {
+	year, month, day := t.Date()
+	at := func(dayOffset, seconds int) time.Time {
+		return time.Date(year, month, day+dayOffset, 0, 0, seconds, 0, t.Location())
+	}
+
+	if w.Start == "" {
+		if !w.matchesDay(t) {
+			return time.Time{}, false
+		}
+		return at(1, 0), true
+	}
+
+	start, _ := clockSeconds(w.Start)
+	end, _ := clockSeconds(w.End)
+	now := t.Hour()*3600 + t.Minute()*60 + t.Second()
+	switch {
+	case start < end:
+		if now >= start && now < end && w.matchesDay(t) {
+			return at(0, end), true
+		}
+	case now >= start:
+		if w.matchesDay(t) {
+			return at(1, end), true
+		}
+	case now < end:
+		// After midnight in a window that started the day before
+		if w.matchesDay(at(-1, 0)) {
+			return at(0, end), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// blackoutEnd reports whether t falls into a blackout window of job and
+// when the blackout ends, following windows that adjoin or overlap.
+func blackoutEnd(job *ScheduledJob, t time.Time) (time.Time, bool) {
+	t = t.In(jobLocation(job))
+	until, blocked := time.Time{}, false
+	// Bounded, since windows may cover every day
+	for i := 0; i < 400; i++ {
+		next := time.Time{}
+		for _, window := range job.BlackoutWindows {
+			if end, ok := window.end(t); ok && end.After(next) {
+				next = end
+			}
+		}
+		if next.IsZero() {
+			break
+		}
+		until, blocked, t = next, true, next
+	}
+	return until, blocked
+}
@@ -0,0 +1,172 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"web-scraper-api/internal/logger"
+)
+
+func TestParseSchedule_TimeZone(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Time zone data not available: %v", err)
+	}
+
+	schedule, err := parseSchedule("0 6 * * *", loc)
+	if err != nil {
+		t.Fatalf("Failed to parse schedule: %v", err)
+	}
+	from := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	next := schedule.Next(from)
+	if want := time.Date(2026, 1, 15, 11, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("Expected 06:00 New York time (%v), got %v", want, next.UTC())
+	}
+
+	at, ok := parseOneShot("2026-11-01T09:00", loc)
+	if !ok {
+		t.Fatal("Should parse the one-shot timestamp")
+	}
+	if at.Location() != loc || at.Hour() != 9 {
+		t.Errorf("Should read the timestamp in the job's time zone, got %v", at)
+	}
+
+	if err := ValidateSchedule("0 6 * * *", "Mars/Olympus_Mons"); err == nil {
+		t.Error("Should reject an unknown time zone")
+	}
+}
+
+func TestBlackoutEnd(t *testing.T) {
+	job := &ScheduledJob{
+		TimeZone: "UTC",
+		BlackoutWindows: []BlackoutWindow{
+			// Weekend maintenance spanning midnight
+			{Days: []string{"sat"}, Start: "22:00", End: "02:00"},
+			// Whole day
+			{Dates: []string{"2026-12-24"}},
+			// Adjoins the whole day
+			{Dates: []string{"2026-12-25"}, Start: "00:00", End: "06:00"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		at      time.Time
+		blocked bool
+		until   time.Time
+	}{
+		{"saturday evening", time.Date(2026, 10, 17, 23, 0, 0, 0, time.UTC), true, time.Date(2026, 10, 18, 2, 0, 0, 0, time.UTC)},
+		{"sunday after midnight", time.Date(2026, 10, 18, 1, 0, 0, 0, time.UTC), true, time.Date(2026, 10, 18, 2, 0, 0, 0, time.UTC)},
+		{"sunday morning", time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC), false, time.Time{}},
+		{"friday evening", time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC), false, time.Time{}},
+		{"monday after midnight", time.Date(2026, 10, 19, 1, 0, 0, 0, time.UTC), false, time.Time{}},
+		{"chained windows", time.Date(2026, 12, 24, 12, 0, 0, 0, time.UTC), true, time.Date(2026, 12, 25, 6, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until, blocked := blackoutEnd(job, tt.at)
+			if blocked != tt.blocked {
+				t.Fatalf("Expected blocked %v, got %v", tt.blocked, blocked)
+			}
+			if blocked && !until.Equal(tt.until) {
+				t.Errorf("Expected the blackout to end at %v, got %v", tt.until, until)
+			}
+		})
+	}
+}
+
+func TestValidateBlackouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		windows []BlackoutWindow
+		policy  BlackoutPolicy
+		wantErr bool
+	}{
+		{"none", nil, "", false},
+		{"recurring", []BlackoutWindow{{Days: []string{"Monday", "tue"}, Start: "01:00", End: "03:30"}}, BlackoutDefer, false},
+		{"dates", []BlackoutWindow{{Dates: []string{"2026-12-24"}}}, BlackoutSkip, false},
+		{"empty window", []BlackoutWindow{{}}, "", true},
+		{"invalid day", []BlackoutWindow{{Days: []string{"someday"}}}, "", true},
+		{"invalid date", []BlackoutWindow{{Dates: []string{"24.12.2026"}}}, "", true},
+		{"start without end", []BlackoutWindow{{Start: "01:00"}}, "", true},
+		{"invalid time", []BlackoutWindow{{Start: "25:00", End: "26:00"}}, "", true},
+		{"invalid policy", nil, "postpone", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBlackouts(tt.windows, tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateBlackouts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScheduler_BlackoutSkipsAndDefers(t *testing.T) {
+	s := NewScheduler(logger.New("error"), nil, nil)
+
+	job := newTestJob("maintenance")
+	job.BlackoutWindows = []BlackoutWindow{{Start: "00:00", End: "23:59"}}
+	if err := s.AddJob(job); err != nil {
+		t.Fatalf("Failed to add job: %v", err)
+	}
+	if _, blocked := blackoutEnd(job, time.Now()); !blocked {
+		t.Skip("Test ran in the last minute of the day")
+	}
+
+	s.runScheduled(job)
+	if len(job.History) != 1 || job.History[0].Status != JobStatusSkipped {
+		t.Fatalf("Should record a skipped run, got %+v", job.History)
+	}
+	if job.RunCount != 0 {
+		t.Errorf("Should not run the job, got %d runs", job.RunCount)
+	}
+
+	job.BlackoutPolicy = BlackoutDefer
+	s.runScheduled(job)
+	if len(job.History) != 2 || job.History[1].Status != JobStatusDeferred {
+		t.Fatalf("Should record a deferred run, got %+v", job.History)
+	}
+	if job.DeferredUntil == nil {
+		t.Fatal("Should set the time of the deferred run")
+	}
+	if _, exists := s.deferred[job.ID]; !exists {
+		t.Error("Should arm a timer for the deferred run")
+	}
+
+	if err := s.PauseJob(job.ID); err != nil {
+		t.Fatalf("Failed to pause job: %v", err)
+	}
+	if job.DeferredUntil != nil || len(s.deferred) != 0 {
+		t.Error("Should cancel the deferred run when the job is paused")
+	}
+}
+
+func TestScheduler_BlackoutChecksQueuedRuns(t *testing.T) {
+	s := NewScheduler(logger.New("error"), nil, nil)
+
+	job := newTestJob("queued")
+	job.BlackoutWindows = []BlackoutWindow{{Start: "00:00", End: "23:59"}}
+	if err := s.AddJob(job); err != nil {
+		t.Fatalf("Failed to add job: %v", err)
+	}
+	if _, blocked := blackoutEnd(job, time.Now()); !blocked {
+		t.Skip("Test ran in the last minute of the day")
+	}
+
+	// A scheduled run that waited for a slot until a window began
+	s.mutex.Lock()
+	s.inFlight[job.ID]++
+	s.queue = append(s.queue, &queuedRun{job: job, queuedAt: time.Now()})
+	s.startQueued()
+	s.mutex.Unlock()
+
+	if len(job.History) != 1 || job.History[0].Status != JobStatusSkipped {
+		t.Fatalf("Should skip the queued run, got %+v", job.History)
+	}
+	if s.active != 0 || len(s.inFlight) != 0 || len(s.queue) != 0 {
+		t.Errorf("Should release the skipped run, got %d active and %d in flight", s.active, len(s.inFlight))
+	}
+}
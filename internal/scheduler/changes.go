@@ -0,0 +1,324 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-scraper-api/internal/scraper"
+)
+
+// maxChangeHistory is the number of change sets kept per job.
+const maxChangeHistory = 50
+
+// ChangeDetection configures what counts as a change between two runs of a
+// job.
+type ChangeDetection struct {
+	// Elements removed from the page before it is compared, e.g. ads or
+	// timestamps
+	IgnoreSelectors []string `json:"ignore_selectors,omitempty"`
+	// Collapse whitespace and drop empty lines before comparing text,
+	// enabled unless set to false
+	NormalizeWhitespace *bool `json:"normalize_whitespace,omitempty"`
+	// Extracted and custom fields to compare, all of them when empty
+	Fields []string `json:"fields,omitempty"`
+	// Relative change below which numeric fields count as unchanged, e.g.
+	// 0.05 ignores changes of up to 5%; FieldThresholds overrides it per field
+	NumericThreshold float64            `json:"numeric_threshold,omitempty"`
+	FieldThresholds  map[string]float64 `json:"field_thresholds,omitempty"`
+	// Don't compare text or links
+	IgnoreText  bool `json:"ignore_text,omitempty"`
+	IgnoreLinks bool `json:"ignore_links,omitempty"`
+	// Minimum score for a run to count as changed
+	MinScore float64 `json:"min_score,omitempty"`
+}
+
+// Validate checks the change detection settings.
+func (c *ChangeDetection) Validate() error {
+	if c.MinScore < 0 || c.MinScore > 1 {
+		return fmt.Errorf("min_score must be between 0 and 1")
+	}
+	if c.NumericThreshold < 0 {
+		return fmt.Errorf("numeric_threshold must not be negative")
+	}
+	for field, threshold := range c.FieldThresholds {
+		if threshold < 0 {
+			return fmt.Errorf("threshold of field %q must not be negative", field)
+		}
+	}
+	return (&scraper.CrawlingOptions{IgnoreSelectors: c.IgnoreSelectors}).Validate()
+}
+
+func (c *ChangeDetection) normalizeWhitespace() bool {
+	return c.NormalizeWhitespace == nil || *c.NormalizeWhitespace
+}
+
+func (c *ChangeDetection) threshold(field string) float64 {
+	if threshold, ok := c.FieldThresholds[field]; ok {
+		return threshold
+	}
+	return c.NumericThreshold
+}
+
+// ValueChange is a changed value.
+type ValueChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// FieldChange is a changed extracted or custom field.
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// TextChange lists the lines added to and removed from the page text.
+type TextChange struct {
+	Added      []string `json:"added,omitempty"`
+	Removed    []string `json:"removed,omitempty"`
+	Similarity float64  `json:"similarity"`
+}
+
+// ChangeSet is the difference between the results of two runs. Score is 0
+// for identical results and 1 for completely different ones.
+type ChangeSet struct {
+	Changed      bool          `json:"changed"`
+	Score        float64       `json:"score"`
+	Title        *ValueChange  `json:"title,omitempty"`
+	Text         *TextChange   `json:"text,omitempty"`
+	Fields       []FieldChange `json:"fields,omitempty"`
+	LinksAdded   []string      `json:"links_added,omitempty"`
+	LinksRemoved []string      `json:"links_removed,omitempty"`
+	PreviousRun  time.Time     `json:"previous_run"`
+	ComparedAt   time.Time     `json:"compared_at"`
+}
+
+// DiffResults compares the results of two runs. A nil config uses the
+// defaults.
+func DiffResults(previous, current *scraper.ScrapedData, config *ChangeDetection) *ChangeSet {
+	if config == nil {
+		config = &ChangeDetection{}
+	}
+
+	changes := &ChangeSet{
+		PreviousRun: previous.ScrapedAt,
+		ComparedAt:  time.Now(),
+	}
+	var scores []float64
+
+	// Title
+	oldTitle, newTitle := previous.Title, current.Title
+	if config.normalizeWhitespace() {
+		oldTitle, newTitle = collapseWhitespace(oldTitle), collapseWhitespace(newTitle)
+	}
+	if oldTitle != newTitle {
+		changes.Title = &ValueChange{Old: previous.Title, New: current.Title}
+		scores = append(scores, 1)
+	} else {
+		scores = append(scores, 0)
+	}
+
+	// Text, compared line by line
+	if !config.IgnoreText {
+		added, removed, similarity := diffLines(
+			textLines(previous.Text, config.normalizeWhitespace()),
+			textLines(current.Text, config.normalizeWhitespace()),
+		)
+		if len(added) > 0 || len(removed) > 0 {
+			changes.Text = &TextChange{Added: added, Removed: removed, Similarity: similarity}
+		}
+		scores = append(scores, 1-similarity)
+	}
+
+	// Extracted and custom fields
+	oldFields, newFields := resultFields(previous), resultFields(current)
+	names := config.Fields
+	if len(names) == 0 {
+		names = fieldNames(oldFields, newFields)
+	}
+	if len(names) > 0 {
+		changed := 0
+		for _, name := range names {
+			oldValue, newValue := oldFields[name], newFields[name]
+			if valuesEqual(oldValue, newValue, config.threshold(name)) {
+				continue
+			}
+			changes.Fields = append(changes.Fields, FieldChange{Field: name, Old: oldValue, New: newValue})
+			changed++
+		}
+		scores = append(scores, float64(changed)/float64(len(names)))
+	}
+
+	// Links
+	if !config.IgnoreLinks {
+		added, removed := diffSets(previous.Links, current.Links)
+		changes.LinksAdded, changes.LinksRemoved = added, removed
+		if union := len(unique(append(append([]string{}, previous.Links...), current.Links...))); union > 0 {
+			scores = append(scores, float64(len(added)+len(removed))/float64(union))
+		}
+	}
+
+	var total float64
+	for _, score := range scores {
+		total += score
+	}
+	changes.Score = math.Round(total/float64(len(scores))*1000) / 1000
+
+	changes.Changed = changes.Title != nil || changes.Text != nil || len(changes.Fields) > 0 ||
+		len(changes.LinksAdded) > 0 || len(changes.LinksRemoved) > 0
+	if changes.Score < config.MinScore {
+		changes.Changed = false
+	}
+	return changes
+}
+
+// resultFields merges the extracted data and the custom selector results,
+// the latter prefixed with "custom.".
+func resultFields(data *scraper.ScrapedData) map[string]interface{} {
+	fields := make(map[string]interface{}, len(data.ExtractedData)+len(data.CustomData))
+	for name, value := range data.ExtractedData {
+		fields[name] = value
+	}
+	for name, value := range data.CustomData {
+		fields["custom."+name] = value
+	}
+	return fields
+}
+
+func fieldNames(a, b map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, fields := range []map[string]interface{}{a, b} {
+		for name := range fields {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// valuesEqual compares two field values. Numbers within the relative
+// threshold of each other are equal.
+func valuesEqual(a, b interface{}, threshold float64) bool {
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			if x == y {
+				return true
+			}
+			base := math.Max(math.Abs(x), math.Abs(y))
+			return threshold > 0 && math.Abs(x-y)/base <= threshold
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func collapseWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func textLines(text string, normalize bool) []string {
+	lines := strings.Split(text, "\n")
+	if !normalize {
+		return lines
+	}
+	normalized := lines[:0]
+	for _, line := range lines {
+		if line = collapseWhitespace(line); line != "" {
+			normalized = append(normalized, line)
+		}
+	}
+	return normalized
+}
+
+// diffLines returns the lines only in b, the lines only in a, and the share
+// of lines both have in common. Repeated lines are counted.
+func diffLines(a, b []string) (added, removed []string, similarity float64) {
+	counts := make(map[string]int, len(a))
+	for _, line := range a {
+		counts[line]++
+	}
+	common := 0
+	for _, line := range b {
+		if counts[line] > 0 {
+			counts[line]--
+			common++
+		} else {
+			added = append(added, line)
+		}
+	}
+	for _, line := range a {
+		if counts[line] > 0 {
+			counts[line]--
+			removed = append(removed, line)
+		}
+	}
+
+	if len(a)+len(b) == 0 {
+		return nil, nil, 1
+	}
+	similarity = float64(2*common) / float64(len(a)+len(b))
+	return added, removed, math.Round(similarity*1000) / 1000
+}
+
+// diffSets returns the values only in b and the values only in a.
+func diffSets(a, b []string) (added, removed []string) {
+	inA, inB := make(map[string]bool), make(map[string]bool)
+	for _, v := range a {
+		inA[v] = true
+	}
+	for _, v := range b {
+		inB[v] = true
+	}
+	for _, v := range unique(b) {
+		if !inA[v] {
+			added = append(added, v)
+		}
+	}
+	for _, v := range unique(a) {
+		if !inB[v] {
+			removed = append(removed, v)
+		}
+	}
+	return added, removed
+}
+
+func unique(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var result []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
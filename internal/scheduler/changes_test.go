@@ -0,0 +1,152 @@
+package scheduler
+
+import (
+	"testing"
+
+	"web-scraper-api/internal/scraper"
+)
+
+func TestDiffResults_Unchanged(t *testing.T) {
+	previous := &scraper.ScrapedData{
+		Title: "Prices",
+		Text:  "Widget\n9.99",
+		Links: []string{"https://example.com/a"},
+	}
+	current := &scraper.ScrapedData{
+		Title: "Prices",
+		Text:  "  Widget  \n\n9.99 ",
+		Links: []string{"https://example.com/a"},
+	}
+
+	changes := DiffResults(previous, current, nil)
+	if changes.Changed {
+		t.Errorf("Should ignore whitespace changes, got %+v", changes)
+	}
+	if changes.Score != 0 {
+		t.Errorf("Expected score 0, got %v", changes.Score)
+	}
+
+	normalize := false
+	changes = DiffResults(previous, current, &ChangeDetection{NormalizeWhitespace: &normalize})
+	if !changes.Changed {
+		t.Error("Should report whitespace changes when normalization is disabled")
+	}
+}
+
+func TestDiffResults_TitleTextAndLinks(t *testing.T) {
+	previous := &scraper.ScrapedData{
+		Title: "Old",
+		Text:  "first\nsecond",
+		Links: []string{"https://example.com/a", "https://example.com/b"},
+	}
+	current := &scraper.ScrapedData{
+		Title: "New",
+		Text:  "first\nthird",
+		Links: []string{"https://example.com/b", "https://example.com/c"},
+	}
+
+	changes := DiffResults(previous, current, nil)
+	if !changes.Changed {
+		t.Fatal("Should report changes")
+	}
+	if changes.Title == nil || changes.Title.Old != "Old" || changes.Title.New != "New" {
+		t.Errorf("Unexpected title change: %+v", changes.Title)
+	}
+	if changes.Text == nil || len(changes.Text.Added) != 1 || changes.Text.Added[0] != "third" ||
+		len(changes.Text.Removed) != 1 || changes.Text.Removed[0] != "second" {
+		t.Errorf("Unexpected text change: %+v", changes.Text)
+	}
+	if changes.Text.Similarity != 0.5 {
+		t.Errorf("Expected similarity 0.5, got %v", changes.Text.Similarity)
+	}
+	if len(changes.LinksAdded) != 1 || changes.LinksAdded[0] != "https://example.com/c" {
+		t.Errorf("Unexpected added links: %v", changes.LinksAdded)
+	}
+	if len(changes.LinksRemoved) != 1 || changes.LinksRemoved[0] != "https://example.com/a" {
+		t.Errorf("Unexpected removed links: %v", changes.LinksRemoved)
+	}
+	if changes.Score <= 0 || changes.Score >= 1 {
+		t.Errorf("Expected a score between 0 and 1, got %v", changes.Score)
+	}
+
+	changes = DiffResults(previous, current, &ChangeDetection{IgnoreText: true, IgnoreLinks: true})
+	if changes.Text != nil || len(changes.LinksAdded) > 0 || len(changes.LinksRemoved) > 0 {
+		t.Errorf("Should ignore text and links, got %+v", changes)
+	}
+	if changes.Score != 1 {
+		t.Errorf("Expected score 1 for a changed title only, got %v", changes.Score)
+	}
+}
+
+func TestDiffResults_NumericThreshold(t *testing.T) {
+	previous := &scraper.ScrapedData{
+		Title:      "Product",
+		CustomData: map[string]string{"price": "100.00", "stock": "10"},
+	}
+	current := &scraper.ScrapedData{
+		Title:      "Product",
+		CustomData: map[string]string{"price": "103.00", "stock": "12"},
+	}
+
+	changes := DiffResults(previous, current, &ChangeDetection{NumericThreshold: 0.05, IgnoreText: true, IgnoreLinks: true})
+	if len(changes.Fields) != 1 || changes.Fields[0].Field != "custom.stock" {
+		t.Errorf("Should only report the stock change above 5%%, got %+v", changes.Fields)
+	}
+
+	changes = DiffResults(previous, current, &ChangeDetection{
+		NumericThreshold: 0.05,
+		FieldThresholds:  map[string]float64{"custom.stock": 0.5},
+		IgnoreText:       true,
+		IgnoreLinks:      true,
+	})
+	if changes.Changed {
+		t.Errorf("Should apply the per-field threshold, got %+v", changes.Fields)
+	}
+
+	changes = DiffResults(previous, current, &ChangeDetection{Fields: []string{"custom.price"}, IgnoreText: true, IgnoreLinks: true})
+	if len(changes.Fields) != 1 || changes.Fields[0].Field != "custom.price" {
+		t.Errorf("Should only compare the configured fields, got %+v", changes.Fields)
+	}
+}
+
+func TestDiffResults_MinScore(t *testing.T) {
+	previous := &scraper.ScrapedData{Title: "Page", Text: "a\nb\nc\nd\ne\nf\ng\nh\ni\nj"}
+	current := &scraper.ScrapedData{Title: "Page", Text: "a\nb\nc\nd\ne\nf\ng\nh\ni\nk"}
+
+	changes := DiffResults(previous, current, nil)
+	if !changes.Changed {
+		t.Fatal("Should report a changed line")
+	}
+
+	changes = DiffResults(previous, current, &ChangeDetection{MinScore: 0.5})
+	if changes.Changed {
+		t.Errorf("Should ignore changes scoring below min_score, got score %v", changes.Score)
+	}
+	if changes.Text == nil {
+		t.Error("Should still list the differences below min_score")
+	}
+}
+
+func TestChangeDetection_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  ChangeDetection
+		wantErr bool
+	}{
+		{"defaults", ChangeDetection{}, false},
+		{"min score above 1", ChangeDetection{MinScore: 1.5}, true},
+		{"negative threshold", ChangeDetection{NumericThreshold: -1}, true},
+		{"negative field threshold", ChangeDetection{FieldThresholds: map[string]float64{"price": -0.1}}, true},
+		{"invalid selector", ChangeDetection{IgnoreSelectors: []string{"[["}}, true},
+		{"valid selector", ChangeDetection{IgnoreSelectors: []string{".ads"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
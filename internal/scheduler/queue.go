@@ -0,0 +1,207 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// OverlapPolicy decides what happens when a job is due while a previous run
+// of it is still in progress.
+type OverlapPolicy string
+
+const (
+	// OverlapSkip drops the new run
+	OverlapSkip OverlapPolicy = "skip"
+	// OverlapQueue runs the job once more after the current run; further
+	// runs are dropped while one is queued
+	OverlapQueue OverlapPolicy = "queue"
+	// OverlapAllow runs the job concurrently with itself
+	OverlapAllow OverlapPolicy = "allow"
+)
+
+// ErrRunInProgress is returned by RunJobNow when a run is dropped because
+// the previous one has not finished.
+var ErrRunInProgress = errors.New("previous run still in progress")
+
+// ErrSchedulerStopped is returned by RunJobNow after Stop.
+var ErrSchedulerStopped = errors.New("scheduler stopped")
+
+// ValidateOverlapPolicy checks an overlap policy. Empty means skip.
+func ValidateOverlapPolicy(policy OverlapPolicy) error {
+	switch policy {
+	case "", OverlapSkip, OverlapQueue, OverlapAllow:
+		return nil
+	}
+	return fmt.Errorf("invalid overlap policy %q", policy)
+}
+
+// queuedRun is a run waiting for a free slot under the global limit or for
+// the previous run of its job.
+type queuedRun struct {
+	job      *ScheduledJob
+	queuedAt time.Time
+	// Started by RunJobNow, so blackout windows don't apply
+	manual bool
+}
+
+// QueuedRun describes a waiting run in the scheduler stats.
+type QueuedRun struct {
+	JobID    string    `json:"job_id"`
+	JobName  string    `json:"job_name"`
+	QueuedAt time.Time `json:"queued_at"`
+	// Waiting for the previous run of the same job rather than a free slot
+	AfterPrevious bool `json:"after_previous,omitempty"`
+}
+
+// SetMaxConcurrentJobs limits the number of runs in progress at once. Runs
+// beyond the limit wait in a queue in the order they became due. Zero or
+// less means no limit.
+func (s *Scheduler) SetMaxConcurrentJobs(n int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.maxConcurrent = n
+	s.startQueued()
+}
+
+// dispatch starts a run of job subject to its overlap policy and the global
+// limit. Scheduled runs that have to wait check the blackout windows again
+// when their turn comes. It is called with the mutex held.
+func (s *Scheduler) dispatch(job *ScheduledJob, manual bool) error {
+	if s.stopped {
+		return ErrSchedulerStopped
+	}
+	if s.inFlight[job.ID] > 0 {
+		switch job.OverlapPolicy {
+		case OverlapAllow:
+		case OverlapQueue:
+			if s.pending[job.ID] == nil {
+				s.pending[job.ID] = &queuedRun{job: job, queuedAt: time.Now(), manual: manual}
+				s.logger.Infof("Scheduled job queued after its current run: %s (%s)", job.Name, job.ID)
+				return nil
+			}
+			s.skipOverlap(job, "previous run still in progress and another one is queued")
+			return ErrRunInProgress
+		default:
+			s.skipOverlap(job, ErrRunInProgress.Error())
+			return ErrRunInProgress
+		}
+	}
+
+	s.inFlight[job.ID]++
+	if s.maxConcurrent > 0 && s.active >= s.maxConcurrent {
+		s.queue = append(s.queue, &queuedRun{job: job, queuedAt: time.Now(), manual: manual})
+		s.logger.Infof("Scheduled job waiting for a free slot: %s (%s)", job.Name, job.ID)
+		return nil
+	}
+	s.start(job)
+	return nil
+}
+
+// start runs job in the background. It is called with the mutex held.
+func (s *Scheduler) start(job *ScheduledJob) {
+	s.active++
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.executeJob(job)
+		s.finish(job)
+	}()
+}
+
+// finish releases the slot of a completed run and starts the runs waiting
+// for it.
+func (s *Scheduler) finish(job *ScheduledJob) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.active--
+	s.release(job)
+	s.startQueued()
+}
+
+// release ends a run of job that finished or was dropped before it started,
+// and dispatches the run queued after it. It is called with the mutex held.
+func (s *Scheduler) release(job *ScheduledJob) {
+	if s.inFlight[job.ID]--; s.inFlight[job.ID] <= 0 {
+		delete(s.inFlight, job.ID)
+	}
+	run := s.pending[job.ID]
+	if run == nil || s.inFlight[job.ID] > 0 {
+		return
+	}
+	delete(s.pending, job.ID)
+	if current, exists := s.jobs[job.ID]; exists && current.Status != JobStatusPaused {
+		if run.manual || !s.blackedOut(current) {
+			s.dispatch(current, run.manual)
+		}
+	}
+}
+
+// startQueued starts waiting runs while slots are free. Scheduled runs that
+// waited into a blackout window are skipped or deferred instead. It is
+// called with the mutex held.
+func (s *Scheduler) startQueued() {
+	for len(s.queue) > 0 && (s.maxConcurrent <= 0 || s.active < s.maxConcurrent) {
+		run := s.queue[0]
+		s.queue = s.queue[1:]
+		if !run.manual && s.blackedOut(run.job) {
+			s.release(run.job)
+			continue
+		}
+		s.start(run.job)
+	}
+}
+
+// dropQueued removes the waiting runs of a job. It is called with the mutex
+// held.
+func (s *Scheduler) dropQueued(jobID string) {
+	delete(s.pending, jobID)
+	queue := s.queue[:0]
+	for _, run := range s.queue {
+		if run.job.ID == jobID {
+			s.inFlight[jobID]--
+			continue
+		}
+		queue = append(queue, run)
+	}
+	s.queue = queue
+	if s.inFlight[jobID] <= 0 {
+		delete(s.inFlight, jobID)
+	}
+}
+
+// skipOverlap records a run dropped by the overlap policy. It is called
+// with the mutex held.
+func (s *Scheduler) skipOverlap(job *ScheduledJob, reason string) {
+	now := time.Now()
+	s.logger.Infof("Scheduled job skipped: %s (%s) - %s", job.Name, job.ID, reason)
+	s.recordRun(job, &JobResult{
+		JobID:     job.ID,
+		JobName:   job.Name,
+		Status:    JobStatusSkipped,
+		Reason:    reason,
+		StartedAt: now,
+		EndedAt:   now,
+	})
+	s.saveJob(job)
+}
+
+// queuedRuns lists the waiting runs, oldest first. It is called with the
+// mutex held.
+func (s *Scheduler) queuedRuns() []QueuedRun {
+	runs := make([]QueuedRun, 0, len(s.queue)+len(s.pending))
+	for _, run := range s.queue {
+		runs = append(runs, QueuedRun{JobID: run.job.ID, JobName: run.job.Name, QueuedAt: run.queuedAt})
+	}
+	for jobID, run := range s.pending {
+		if job, exists := s.jobs[jobID]; exists {
+			runs = append(runs, QueuedRun{JobID: jobID, JobName: job.Name, QueuedAt: run.queuedAt, AfterPrevious: true})
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].QueuedAt.Before(runs[j].QueuedAt)
+	})
+	return runs
+}
@@ -0,0 +1,141 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"web-scraper-api/internal/config"
+	"web-scraper-api/internal/logger"
+	"web-scraper-api/internal/scraper"
+)
+
+// newBlockingScheduler returns a scheduler whose jobs scrape a server that
+// answers once release is closed or the request is cancelled.
+func newBlockingScheduler(t *testing.T) (*Scheduler, string, chan struct{}) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><title>Slow</title></head><body></body></html>`)
+	}))
+	t.Cleanup(server.Close)
+
+	service := scraper.NewServiceWithConfig(&config.Config{
+		Scraping: config.ScrapingConfig{AllowPrivateNetworks: true},
+	}, logger.New("error"))
+	return NewScheduler(logger.New("error"), service, nil), server.URL, release
+}
+
+func addBlockingJob(t *testing.T, s *Scheduler, url string, policy OverlapPolicy) *ScheduledJob {
+	job := newTestJob("slow")
+	job.URL = url
+	job.OverlapPolicy = policy
+	if err := s.AddJob(job); err != nil {
+		t.Fatalf("Failed to add job: %v", err)
+	}
+	return job
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %s", what)
+}
+
+func runCount(s *Scheduler, job *ScheduledJob) int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return job.RunCount
+}
+
+func TestScheduler_OverlapSkip(t *testing.T) {
+	s, url, release := newBlockingScheduler(t)
+	job := addBlockingJob(t, s, url, "")
+
+	if err := s.RunJobNow(job.ID); err != nil {
+		t.Fatalf("Failed to run job: %v", err)
+	}
+	if err := s.RunJobNow(job.ID); !errors.Is(err, ErrRunInProgress) {
+		t.Errorf("Expected ErrRunInProgress, got %v", err)
+	}
+
+	close(release)
+	waitFor(t, "the run to finish", func() bool { return runCount(s, job) == 1 })
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	skipped := 0
+	for _, run := range job.History {
+		if run.Status == JobStatusSkipped {
+			skipped++
+		}
+	}
+	if skipped != 1 {
+		t.Errorf("Expected 1 skipped run in the history, got %d", skipped)
+	}
+}
+
+func TestScheduler_OverlapQueueOne(t *testing.T) {
+	s, url, release := newBlockingScheduler(t)
+	job := addBlockingJob(t, s, url, OverlapQueue)
+
+	if err := s.RunJobNow(job.ID); err != nil {
+		t.Fatalf("Failed to run job: %v", err)
+	}
+	if err := s.RunJobNow(job.ID); err != nil {
+		t.Errorf("Should queue the second run, got %v", err)
+	}
+	if err := s.RunJobNow(job.ID); !errors.Is(err, ErrRunInProgress) {
+		t.Errorf("Should drop the third run, got %v", err)
+	}
+	if queued := s.GetJobStats()["queued_runs"]; queued != 1 {
+		t.Errorf("Expected 1 queued run, got %v", queued)
+	}
+
+	close(release)
+	waitFor(t, "both runs to finish", func() bool { return runCount(s, job) == 2 })
+	time.Sleep(50 * time.Millisecond)
+	if count := runCount(s, job); count != 2 {
+		t.Errorf("Expected 2 runs, got %d", count)
+	}
+}
+
+func TestScheduler_MaxConcurrentJobs(t *testing.T) {
+	s, url, release := newBlockingScheduler(t)
+	s.SetMaxConcurrentJobs(1)
+	first := addBlockingJob(t, s, url, "")
+	second := addBlockingJob(t, s, url, "")
+
+	for _, job := range []*ScheduledJob{first, second} {
+		if err := s.RunJobNow(job.ID); err != nil {
+			t.Fatalf("Failed to run job: %v", err)
+		}
+	}
+
+	stats := s.GetJobStats()
+	if stats["running_runs"] != 1 || stats["queued_runs"] != 1 {
+		t.Errorf("Expected 1 running and 1 queued run, got %v and %v", stats["running_runs"], stats["queued_runs"])
+	}
+	if queue := stats["queue"].([]QueuedRun); len(queue) != 1 || queue[0].JobID != second.ID {
+		t.Errorf("Expected the second job to wait, got %+v", queue)
+	}
+
+	close(release)
+	waitFor(t, "both jobs to run", func() bool {
+		return runCount(s, first) == 1 && runCount(s, second) == 1
+	})
+	waitFor(t, "the slots to be released", func() bool { return s.GetJobStats()["running_runs"] == 0 })
+}
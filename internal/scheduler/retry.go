@@ -0,0 +1,266 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"web-scraper-api/internal/scraper"
+)
+
+const (
+	defaultJobRetryAttempts    = 3
+	defaultJobRetryBaseBackoff = 30 * time.Second
+	defaultJobRetryMaxBackoff  = 10 * time.Minute
+	webhookTimeout             = 10 * time.Second
+)
+
+// defaultJobRetryableErrors are the failures retried when a policy doesn't
+// list any; parse, policy and auth failures won't go away by themselves.
+var defaultJobRetryableErrors = []scraper.ErrorKind{
+	scraper.ErrorKindNetwork,
+	scraper.ErrorKindTimeout,
+	scraper.ErrorKindHTTPStatus,
+}
+
+// RetryPolicy configures how failed runs of a job are retried before its
+// next scheduled run. It is separate from the retries of single requests
+// in the scrape options. Zero values are replaced by the defaults; a nil
+// policy disables retries.
+type RetryPolicy struct {
+	// Attempts per run including the first one
+	MaxAttempts int           `json:"max_attempts"`
+	BaseBackoff time.Duration `json:"base_backoff"`
+	MaxBackoff  time.Duration `json:"max_backoff"`
+	// Error kinds worth retrying, network, timeout and HTTP status errors
+	// when empty
+	RetryableErrors []scraper.ErrorKind `json:"retryable_errors,omitempty"`
+}
+
+// ValidateRetry checks the retry policy of a job and the number of failed
+// runs after which it is paused. A nil policy disables retries.
+func ValidateRetry(policy *RetryPolicy, pauseAfterFailures int) error {
+	if pauseAfterFailures < 0 {
+		return fmt.Errorf("pause_after_failures must not be negative")
+	}
+	if policy == nil {
+		return nil
+	}
+	if policy.MaxAttempts < 0 || policy.BaseBackoff < 0 || policy.MaxBackoff < 0 {
+		return fmt.Errorf("retry settings must not be negative")
+	}
+	for _, kind := range policy.RetryableErrors {
+		switch kind {
+		case scraper.ErrorKindNetwork, scraper.ErrorKindTimeout, scraper.ErrorKindHTTPStatus,
+			scraper.ErrorKindParse, scraper.ErrorKindPolicy, scraper.ErrorKindAuth:
+		default:
+			return fmt.Errorf("invalid retryable error kind %q", kind)
+		}
+	}
+	return nil
+}
+
+// withDefaults returns a copy of the policy with all unset fields filled in.
+func (p *RetryPolicy) withDefaults() *RetryPolicy {
+	policy := *p
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = defaultJobRetryAttempts
+	}
+	if policy.BaseBackoff <= 0 {
+		policy.BaseBackoff = defaultJobRetryBaseBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = defaultJobRetryMaxBackoff
+	}
+	if len(policy.RetryableErrors) == 0 {
+		policy.RetryableErrors = defaultJobRetryableErrors
+	}
+	return &policy
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	kind := scraper.ErrorKindOf(err)
+	for _, k := range p.RetryableErrors {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the wait before the given retry (1 for the first retry):
+// exponential growth from BaseBackoff, capped at MaxBackoff.
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	d := float64(p.BaseBackoff) * math.Pow(2, float64(retry-1))
+	if d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	return time.Duration(d)
+}
+
+// handleFailure retries a failed run if the retry policy of the job allows
+// it before the next scheduled run, and otherwise counts the failure
+// towards the circuit breaker. It is called with the mutex held.
+func (s *Scheduler) handleFailure(job *ScheduledJob, result *JobResult, err error) {
+	if job.Retry != nil && !s.stopped {
+		policy := job.Retry.withDefaults()
+		if result.Attempt < policy.MaxAttempts && policy.retryable(err) {
+			delay := policy.backoff(result.Attempt)
+			retryAt := time.Now().Add(delay)
+			if job.NextRun == nil || retryAt.Before(*job.NextRun) {
+				s.attempts[job.ID] = result.Attempt
+				s.scheduleRetry(job, retryAt)
+				result.Reason = fmt.Sprintf("attempt %d of %d failed, retrying at %s", result.Attempt, policy.MaxAttempts, retryAt.Format(time.RFC3339))
+				s.logger.Infof("Scheduled job will be retried: %s (%s) - %s", job.Name, job.ID, result.Reason)
+				return
+			}
+		}
+	}
+
+	delete(s.attempts, job.ID)
+	job.ConsecutiveFailures++
+	if job.PauseAfterFailures > 0 && job.ConsecutiveFailures >= job.PauseAfterFailures {
+		s.autoPause(job, result)
+	}
+}
+
+// scheduleRetry runs job again at the given time. It is called with the
+// mutex held.
+func (s *Scheduler) scheduleRetry(job *ScheduledJob, at time.Time) {
+	if timer, exists := s.retries[job.ID]; exists {
+		timer.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Until(at), func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		if s.retries[job.ID] != timer {
+			return
+		}
+		delete(s.retries, job.ID)
+		job.NextRetry = nil
+		if current, exists := s.jobs[job.ID]; !exists || current != job || job.Status == JobStatusPaused {
+			delete(s.attempts, job.ID)
+			return
+		}
+		if !s.blackedOut(job) {
+			s.dispatch(job, false)
+		}
+	})
+	s.retries[job.ID] = timer
+	job.NextRetry = &at
+}
+
+// cancelRetry drops the pending retry of a job and starts the next run with
+// a fresh attempt count. It is called with the mutex held.
+func (s *Scheduler) cancelRetry(jobID string) {
+	if timer, exists := s.retries[jobID]; exists {
+		timer.Stop()
+		delete(s.retries, jobID)
+	}
+	delete(s.attempts, jobID)
+	if job, exists := s.jobs[jobID]; exists {
+		job.NextRetry = nil
+	}
+}
+
+// autoPause pauses a job whose runs keep failing and notifies about it. It
+// is called with the mutex held.
+func (s *Scheduler) autoPause(job *ScheduledJob, result *JobResult) {
+	if entryID, exists := s.jobEntries[job.ID]; exists {
+		s.cron.Remove(entryID)
+		delete(s.jobEntries, job.ID)
+	}
+	s.cancelDeferred(job.ID)
+	s.dropQueued(job.ID)
+	s.cancelRetry(job.ID)
+
+	job.Status = JobStatusPaused
+	job.NextRun = nil
+	job.PausedReason = fmt.Sprintf("paused after %d consecutive failed runs, last error: %s", job.ConsecutiveFailures, result.Error)
+
+	s.logger.Warnf("Scheduled job paused: %s (%s) - %s", job.Name, job.ID, job.PausedReason)
+
+	event := &JobResult{
+		JobID:     job.ID,
+		JobName:   job.Name,
+		Status:    JobStatusPaused,
+		Error:     result.Error,
+		Reason:    job.PausedReason,
+		StartedAt: result.StartedAt,
+		EndedAt:   result.EndedAt,
+		Duration:  result.Duration,
+	}
+	if s.onJobPaused != nil {
+		s.onJobPaused(event)
+	}
+	s.sendWebhook(job, event)
+}
+
+// SetPauseCallback sets the callback for jobs paused after repeated
+// failures.
+func (s *Scheduler) SetPauseCallback(onJobPaused func(*JobResult)) {
+	s.onJobPaused = onJobPaused
+}
+
+// SetWebhookURL sets the URL that notifications about automatically paused
+// jobs are posted to. An empty URL disables them.
+func (s *Scheduler) SetWebhookURL(url string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.webhookURL = url
+}
+
+// webhookEvent is the JSON body posted to the webhook.
+type webhookEvent struct {
+	Event               string    `json:"event"`
+	JobID               string    `json:"job_id"`
+	JobName             string    `json:"job_name"`
+	URL                 string    `json:"url"`
+	Reason              string    `json:"reason"`
+	Error               string    `json:"error,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Time                time.Time `json:"time"`
+}
+
+// sendWebhook posts a job paused event in the background. It is called with
+// the mutex held.
+func (s *Scheduler) sendWebhook(job *ScheduledJob, event *JobResult) {
+	if s.webhookURL == "" {
+		return
+	}
+
+	body, err := json.Marshal(webhookEvent{
+		Event:               "scheduled_job_paused",
+		JobID:               job.ID,
+		JobName:             job.Name,
+		URL:                 job.URL,
+		Reason:              event.Reason,
+		Error:               event.Error,
+		ConsecutiveFailures: job.ConsecutiveFailures,
+		Time:                time.Now(),
+	})
+	if err != nil {
+		s.logger.Errorf("Failed to encode webhook event: %v", err)
+		return
+	}
+
+	url := s.webhookURL
+	go func() {
+		client := &http.Client{Timeout: webhookTimeout}
+		resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			s.logger.Warnf("Failed to send webhook for job %s: %v", event.JobID, err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			s.logger.Warnf("Webhook for job %s returned status %d", event.JobID, resp.StatusCode)
+		}
+	}()
+}
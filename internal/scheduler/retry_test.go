@@ -0,0 +1,221 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web-scraper-api/internal/config"
+	"web-scraper-api/internal/logger"
+	"web-scraper-api/internal/scraper"
+)
+
+// newFlakyScheduler returns a scheduler whose jobs scrape a server that drops
+// the connection of the first failures requests and answers the rest.
+func newFlakyScheduler(t *testing.T, failures int32) (*Scheduler, string) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= failures {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><title>Flaky</title></head><body></body></html>`)
+	}))
+	t.Cleanup(server.Close)
+
+	service := scraper.NewServiceWithConfig(&config.Config{
+		Scraping: config.ScrapingConfig{AllowPrivateNetworks: true},
+	}, logger.New("error"))
+	return NewScheduler(logger.New("error"), service, nil), server.URL
+}
+
+func jobStatus(s *Scheduler, job *ScheduledJob) JobStatus {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return job.Status
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := (&RetryPolicy{BaseBackoff: time.Second, MaxBackoff: 5 * time.Second}).withDefaults()
+
+	for retry, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second} {
+		if got := policy.backoff(retry); got != want {
+			t.Errorf("Expected a backoff of %v before retry %d, got %v", want, retry, got)
+		}
+	}
+	if policy.MaxAttempts != defaultJobRetryAttempts {
+		t.Errorf("Expected %d attempts by default, got %d", defaultJobRetryAttempts, policy.MaxAttempts)
+	}
+	if policy.retryable(&scraper.ScrapeError{Kind: scraper.ErrorKindPolicy}) {
+		t.Error("Should not retry policy errors by default")
+	}
+}
+
+func TestValidateRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     *RetryPolicy
+		pauseAfter int
+		wantErr    bool
+	}{
+		{"none", nil, 0, false},
+		{"defaults", &RetryPolicy{}, 3, false},
+		{"error kinds", &RetryPolicy{RetryableErrors: []scraper.ErrorKind{scraper.ErrorKindParse}}, 0, false},
+		{"negative attempts", &RetryPolicy{MaxAttempts: -1}, 0, true},
+		{"negative backoff", &RetryPolicy{BaseBackoff: -time.Second}, 0, true},
+		{"invalid error kind", &RetryPolicy{RetryableErrors: []scraper.ErrorKind{"flaky"}}, 0, true},
+		{"negative pause threshold", nil, -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRetry(tt.policy, tt.pauseAfter)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRetry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScheduler_RetryThenSucceed(t *testing.T) {
+	s, url := newFlakyScheduler(t, 1)
+
+	job := newTestJob("flaky")
+	job.URL = url
+	job.Retry = &RetryPolicy{MaxAttempts: 3, BaseBackoff: 20 * time.Millisecond}
+	job.PauseAfterFailures = 1
+	if err := s.AddJob(job); err != nil {
+		t.Fatalf("Failed to add job: %v", err)
+	}
+
+	if err := s.RunJobNow(job.ID); err != nil {
+		t.Fatalf("Failed to run job: %v", err)
+	}
+	waitFor(t, "the retry to succeed", func() bool { return jobStatus(s, job) == JobStatusComplete })
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if job.RunCount != 2 {
+		t.Errorf("Expected 2 runs, got %d", job.RunCount)
+	}
+	if job.ConsecutiveFailures != 0 || job.NextRetry != nil {
+		t.Errorf("Should reset the failures after a successful retry, got %d failures", job.ConsecutiveFailures)
+	}
+	if len(job.History) != 2 || job.History[0].Reason == "" || job.History[1].Attempt != 2 {
+		t.Errorf("Should record the failed attempt and the retry, got %+v", job.History)
+	}
+}
+
+func TestScheduler_PauseAfterFailures(t *testing.T) {
+	s, url := newFlakyScheduler(t, 100)
+
+	var (
+		mu     sync.Mutex
+		paused *JobResult
+		event  webhookEvent
+	)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		json.NewDecoder(r.Body).Decode(&event)
+	}))
+	defer webhook.Close()
+	s.SetWebhookURL(webhook.URL)
+	s.SetPauseCallback(func(result *JobResult) { paused = result })
+
+	job := newTestJob("broken")
+	job.URL = url
+	job.Retry = &RetryPolicy{MaxAttempts: 2, BaseBackoff: 10 * time.Millisecond}
+	job.PauseAfterFailures = 2
+	if err := s.AddJob(job); err != nil {
+		t.Fatalf("Failed to add job: %v", err)
+	}
+
+	// Both attempts of a run count as one failure
+	if err := s.RunJobNow(job.ID); err != nil {
+		t.Fatalf("Failed to run job: %v", err)
+	}
+	waitFor(t, "the retry to fail", func() bool { return runCount(s, job) == 2 })
+	waitFor(t, "the run to finish", func() bool { return s.GetJobStats()["running_runs"] == 0 })
+	if status := jobStatus(s, job); status != JobStatusError {
+		t.Fatalf("Should not pause after one failed run, got %s", status)
+	}
+
+	if err := s.RunJobNow(job.ID); err != nil {
+		t.Fatalf("Failed to run job: %v", err)
+	}
+	waitFor(t, "the job to be paused", func() bool { return jobStatus(s, job) == JobStatusPaused })
+	waitFor(t, "the webhook", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return event.JobID != ""
+	})
+
+	s.mutex.RLock()
+	if job.ConsecutiveFailures != 2 || job.PausedReason == "" || job.NextRun != nil {
+		t.Errorf("Should pause with a reason, got %d failures and %q", job.ConsecutiveFailures, job.PausedReason)
+	}
+	if paused == nil || paused.Reason != job.PausedReason {
+		t.Errorf("Should call the pause callback with the reason, got %+v", paused)
+	}
+	s.mutex.RUnlock()
+
+	mu.Lock()
+	if event.Event != "scheduled_job_paused" || event.JobID != job.ID || event.ConsecutiveFailures != 2 {
+		t.Errorf("Unexpected webhook event %+v", event)
+	}
+	mu.Unlock()
+
+	if err := s.ResumeJob(job.ID); err != nil {
+		t.Fatalf("Failed to resume job: %v", err)
+	}
+	if job.ConsecutiveFailures != 0 || job.PausedReason != "" {
+		t.Error("Should reset the circuit breaker when the job is resumed")
+	}
+}
+
+func TestScheduler_RetryInBlackoutWindow(t *testing.T) {
+	s, url := newFlakyScheduler(t, 100)
+
+	job := newTestJob("retry blackout")
+	job.URL = url
+	job.Retry = &RetryPolicy{MaxAttempts: 3, BaseBackoff: 10 * time.Millisecond}
+	job.BlackoutWindows = []BlackoutWindow{{Start: "00:00", End: "23:59"}}
+	if err := s.AddJob(job); err != nil {
+		t.Fatalf("Failed to add job: %v", err)
+	}
+	if _, blocked := blackoutEnd(job, time.Now()); !blocked {
+		t.Skip("Test ran in the last minute of the day")
+	}
+
+	// Manual runs ignore the window, their retries don't
+	if err := s.RunJobNow(job.ID); err != nil {
+		t.Fatalf("Failed to run job: %v", err)
+	}
+	waitFor(t, "the retry to be skipped", func() bool {
+		s.mutex.RLock()
+		defer s.mutex.RUnlock()
+		return len(job.History) == 2
+	})
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if job.RunCount != 1 {
+		t.Errorf("Should not retry inside a blackout window, got %d runs", job.RunCount)
+	}
+	if job.History[1].Status != JobStatusSkipped {
+		t.Errorf("Expected the retry to be skipped, got %+v", job.History[1])
+	}
+	if len(s.retries) != 0 || job.NextRetry != nil {
+		t.Error("Should not schedule further retries")
+	}
+}
@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// scheduleParser accepts standard 5-field cron expressions, 6-field ones
+// with a leading seconds field, and descriptors like @hourly or @every 90s.
+var scheduleParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// oneShotLayouts are the accepted formats of one-shot schedules.
+var oneShotLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// onceSchedule runs a job a single time.
+type onceSchedule struct {
+	at time.Time
+}
+
+// Next returns the time of the run, or the zero time once it has passed,
+// which keeps the entry from running again.
+func (o onceSchedule) Next(t time.Time) time.Time {
+	if t.Before(o.at) {
+		return o.at
+	}
+	return time.Time{}
+}
+
+// parseOneShot parses a one-shot schedule. Timestamps without a zone are
+// read in loc.
+func parseOneShot(spec string, loc *time.Location) (time.Time, bool) {
+	spec = strings.TrimSpace(spec)
+	for _, layout := range oneShotLayouts {
+		if at, err := time.ParseInLocation(layout, spec, loc); err == nil {
+			return at, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseSchedule parses a schedule spec: a cron expression with 5 or 6
+// fields, a descriptor, or a one-shot timestamp. Cron expressions and
+// descriptors are evaluated in loc.
+func parseSchedule(spec string, loc *time.Location) (cron.Schedule, error) {
+	if at, ok := parseOneShot(spec, loc); ok {
+		return onceSchedule{at: at}, nil
+	}
+	schedule, err := scheduleParser.Parse(strings.TrimSpace(spec))
+	if err != nil {
+		return nil, err
+	}
+	// An explicit CRON_TZ= prefix takes precedence
+	if specSchedule, ok := schedule.(*cron.SpecSchedule); ok && specSchedule.Location == time.Local {
+		specSchedule.Location = loc
+	}
+	return schedule, nil
+}
+
+// loadLocation returns the time zone with the given IANA name, or the local
+// time zone for an empty name.
+func loadLocation(timeZone string) (*time.Location, error) {
+	if timeZone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q", timeZone)
+	}
+	return loc, nil
+}
+
+// jobLocation returns the time zone of job. Time zones are validated when
+// jobs are added, so an unknown one falls back to local time.
+func jobLocation(job *ScheduledJob) *time.Location {
+	loc, err := loadLocation(job.TimeZone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// ValidateSchedule checks that spec is a valid schedule in the given time
+// zone with at least one run in the future.
+func ValidateSchedule(spec, timeZone string) error {
+	loc, err := loadLocation(timeZone)
+	if err != nil {
+		return err
+	}
+	schedule, err := parseSchedule(spec, loc)
+	if err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return fmt.Errorf("invalid schedule: %s is in the past", strings.TrimSpace(spec))
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"web-scraper-api/internal/config"
+	"web-scraper-api/internal/logger"
+	"web-scraper-api/internal/scraper"
+)
+
+func TestValidateSchedule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{"0 0 * * *", false},
+		{"*/15 * * * *", false},
+		{"30 0 0 * * *", false},
+		{"*/10 * * * * *", false},
+		{"@hourly", false},
+		{"@every 90s", false},
+		{time.Now().Add(time.Hour).Format(time.RFC3339), false},
+		{time.Now().Add(time.Hour).Format("2006-01-02T15:04"), false},
+		{time.Now().Add(-time.Hour).Format(time.RFC3339), true},
+		{"* * * *", true},
+		{"0 0 0 0 * * *", true},
+		{"@fortnightly", true},
+		{"", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			err := ValidateSchedule(tt.spec, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSchedule(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSchedule_Seconds(t *testing.T) {
+	schedule, err := parseSchedule("*/10 * * * * *", time.UTC)
+	if err != nil {
+		t.Fatalf("Failed to parse schedule: %v", err)
+	}
+	from := time.Date(2026, 1, 1, 12, 0, 1, 0, time.UTC)
+	if next := schedule.Next(from); !next.Equal(from.Add(9 * time.Second)) {
+		t.Errorf("Expected the next run at %v, got %v", from.Add(9*time.Second), next)
+	}
+
+	schedule, err = parseSchedule("0 * * * *", time.UTC)
+	if err != nil {
+		t.Fatalf("Failed to parse schedule: %v", err)
+	}
+	if next := schedule.Next(from); !next.Equal(from.Add(59*time.Minute + 59*time.Second)) {
+		t.Errorf("Should treat 5 fields as a standard expression, got %v", next)
+	}
+}
+
+func TestOnceSchedule(t *testing.T) {
+	at := time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC)
+	schedule := onceSchedule{at: at}
+
+	if next := schedule.Next(at.Add(-time.Minute)); !next.Equal(at) {
+		t.Errorf("Expected %v, got %v", at, next)
+	}
+	if next := schedule.Next(at); !next.IsZero() {
+		t.Errorf("Should not run again, got %v", next)
+	}
+}
+
+func TestScheduler_OneShotCompletes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><title>Once</title></head><body></body></html>`)
+	}))
+	defer server.Close()
+
+	service := scraper.NewServiceWithConfig(&config.Config{
+		Scraping: config.ScrapingConfig{AllowPrivateNetworks: true},
+	}, logger.New("error"))
+	s := NewScheduler(logger.New("error"), service, nil)
+
+	job := newTestJob("once")
+	job.URL = server.URL
+	job.Schedule = time.Now().Add(200 * time.Millisecond).Format(time.RFC3339Nano)
+	if err := s.AddJob(job); err != nil {
+		t.Fatalf("Failed to add job: %v", err)
+	}
+	if job.NextRun == nil {
+		t.Fatal("Should schedule the one-shot job")
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	s.executeJob(job)
+
+	if job.Status != JobStatusComplete {
+		t.Errorf("Expected status %s, got %s (%s)", JobStatusComplete, job.Status, job.LastError)
+	}
+	if job.NextRun != nil {
+		t.Errorf("Should not schedule another run, got %v", job.NextRun)
+	}
+	if _, exists := s.jobEntries[job.ID]; exists {
+		t.Error("Should remove the job from the cron scheduler")
+	}
+	if err := s.ResumeJob(job.ID); err == nil {
+		t.Error("Should not resume a finished one-shot job")
+	}
+}
@@ -3,6 +3,7 @@ package scheduler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -21,13 +22,34 @@ const (
 	JobStatusRunning  JobStatus = "running"
 	JobStatusError    JobStatus = "error"
 	JobStatusComplete JobStatus = "complete"
+	// The last run was cancelled through CancelJob or by a shutdown
+	JobStatusCancelled JobStatus = "cancelled"
+	// Statuses of runs that did not happen because of a blackout window
+	JobStatusSkipped  JobStatus = "skipped"
+	JobStatusDeferred JobStatus = "deferred"
 )
 
+// maxRunHistory is the number of runs kept in the history of a job.
+const maxRunHistory = 50
+
+// defaultJobTimeout is the deadline of runs whose options don't set one.
+const defaultJobTimeout = 5 * time.Minute
+
+// ErrJobNotRunning is returned by CancelJob when a job has no run in
+// progress.
+var ErrJobNotRunning = errors.New("job is not running")
+
+// activeRun is a run in progress that can be cancelled.
+type activeRun struct {
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
 type ScheduledJob struct {
 	ID          string                   `json:"id"`
 	Name        string                   `json:"name"`
 	Description string                   `json:"description"`
-	Schedule    string                   `json:"schedule"` // Cron expression
+	Schedule    string                   `json:"schedule"` // Cron expression, descriptor or one-shot timestamp
 	URL         string                   `json:"url"`
 	Options     *scraper.CrawlingOptions `json:"options"`
 	Status      JobStatus                `json:"status"`
@@ -40,6 +62,28 @@ type ScheduledJob struct {
 	UpdatedAt   time.Time                `json:"updated_at"`
 	Results     []*scraper.ScrapedData   `json:"results,omitempty"`
 	LastResult  *scraper.ScrapedData     `json:"last_result,omitempty"`
+	// Change detection between consecutive runs
+	ChangeDetection *ChangeDetection `json:"change_detection,omitempty"`
+	Changes         []*ChangeSet     `json:"changes,omitempty"`
+	LastChangedAt   *time.Time       `json:"last_changed_at,omitempty"`
+	// IANA time zone the schedule and blackout windows are evaluated in,
+	// the server's local time zone when empty
+	TimeZone        string           `json:"time_zone,omitempty"`
+	BlackoutWindows []BlackoutWindow `json:"blackout_windows,omitempty"`
+	BlackoutPolicy  BlackoutPolicy   `json:"blackout_policy,omitempty"`
+	DeferredUntil   *time.Time       `json:"deferred_until,omitempty"`
+	// What to do when the job is due while it is still running, skip when
+	// empty
+	OverlapPolicy OverlapPolicy `json:"overlap_policy,omitempty"`
+	// Retries of failed runs, and the number of consecutive failed runs
+	// after which the job is paused, 0 to never pause
+	Retry               *RetryPolicy `json:"retry,omitempty"`
+	NextRetry           *time.Time   `json:"next_retry,omitempty"`
+	PauseAfterFailures  int          `json:"pause_after_failures,omitempty"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	PausedReason        string       `json:"paused_reason,omitempty"`
+	// Recent runs without their data, including skipped and deferred ones
+	History []*JobResult `json:"history,omitempty"`
 }
 
 type JobResult struct {
@@ -47,7 +91,10 @@ type JobResult struct {
 	JobName   string               `json:"job_name"`
 	Status    JobStatus            `json:"status"`
 	Data      *scraper.ScrapedData `json:"data,omitempty"`
+	Changes   *ChangeSet           `json:"changes,omitempty"`
 	Error     string               `json:"error,omitempty"`
+	Reason    string               `json:"reason,omitempty"`
+	Attempt   int                  `json:"attempt,omitempty"`
 	StartedAt time.Time            `json:"started_at"`
 	EndedAt   time.Time            `json:"ended_at"`
 	Duration  time.Duration        `json:"duration"`
@@ -57,22 +104,101 @@ type Scheduler struct {
 	cron       *cron.Cron
 	jobs       map[string]*ScheduledJob
 	jobEntries map[string]cron.EntryID
+	deferred   map[string]*time.Timer
+	runs       map[string][]*activeRun
 	mutex      sync.RWMutex
 	logger     *logger.Logger
 	scraper    *scraper.Service
+	store      JobStore
+	// Runs in progress and waiting, see queue.go
+	maxConcurrent int
+	active        int
+	inFlight      map[string]int
+	pending       map[string]*queuedRun
+	queue         []*queuedRun
+	// Pending retries of failed runs and the attempts made so far
+	retries    map[string]*time.Timer
+	attempts   map[string]int
+	webhookURL string
+	// Parent context of all runs, cancelled by Stop
+	ctx     context.Context
+	stopAll context.CancelFunc
+	stopped bool
+	wg      sync.WaitGroup
 	// Callbacks for external integrations
 	onJobStart    func(*JobResult)
 	onJobComplete func(*JobResult)
 	onJobError    func(*JobResult)
+	onJobChanged  func(*JobResult)
+	onJobPaused   func(*JobResult)
 }
 
-func NewScheduler(logger *logger.Logger, scraper *scraper.Service) *Scheduler {
-	return &Scheduler{
-		cron:       cron.New(cron.WithSeconds()),
+// NewScheduler creates a scheduler and schedules the jobs kept in store. A
+// nil store keeps jobs in memory only.
+func NewScheduler(logger *logger.Logger, scraper *scraper.Service, store JobStore) *Scheduler {
+	if store == nil {
+		store = NewMemoryJobStore()
+	}
+
+	ctx, stopAll := context.WithCancel(context.Background())
+	s := &Scheduler{
+		ctx:        ctx,
+		stopAll:    stopAll,
+		cron:       cron.New(),
 		jobs:       make(map[string]*ScheduledJob),
 		jobEntries: make(map[string]cron.EntryID),
+		deferred:   make(map[string]*time.Timer),
+		runs:       make(map[string][]*activeRun),
+		inFlight:   make(map[string]int),
+		pending:    make(map[string]*queuedRun),
+		retries:    make(map[string]*time.Timer),
+		attempts:   make(map[string]int),
 		logger:     logger,
 		scraper:    scraper,
+		store:      store,
+	}
+	s.loadJobs()
+	return s
+}
+
+// loadJobs schedules the stored jobs. Runs interrupted by a restart are not
+// resumed; the job waits for its next scheduled run.
+func (s *Scheduler) loadJobs() {
+	jobs, err := s.store.LoadJobs()
+	if err != nil {
+		s.logger.Errorf("Failed to load scheduled jobs: %v", err)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, job := range jobs {
+		if job.Status == JobStatusRunning {
+			job.Status = JobStatusActive
+		}
+		// Pending retries don't survive a restart; the next scheduled run
+		// takes their place
+		job.NextRetry = nil
+		// Jobs stored by earlier versions hold redacted credentials
+		s.pauseUnusable(job, "restart")
+
+		if err := s.addJobInternal(job); err != nil {
+			s.logger.Errorf("Failed to load job %s: %v", job.ID, err)
+			continue
+		}
+		if job.DeferredUntil != nil {
+			if job.Status == JobStatusPaused {
+				job.DeferredUntil = nil
+			} else {
+				s.deferRun(job, *job.DeferredUntil)
+			}
+		}
+		s.saveJob(job)
+	}
+
+	if len(s.jobs) > 0 {
+		s.logger.Infof("Loaded %d scheduled jobs", len(s.jobs))
 	}
 }
 
@@ -81,20 +207,75 @@ func (s *Scheduler) Start() {
 	s.logger.Info("Scheduler started")
 }
 
-func (s *Scheduler) Stop() {
+// Stop stops the scheduler, cancels the runs in progress and waits for
+// them to finish until ctx is done, then closes the job store.
+func (s *Scheduler) Stop(ctx context.Context) error {
 	s.cron.Stop()
+	s.mutex.Lock()
+	s.stopped = true
+	for jobID := range s.deferred {
+		s.cancelDeferred(jobID)
+	}
+	for jobID := range s.jobs {
+		s.dropQueued(jobID)
+		s.cancelRetry(jobID)
+		s.cancelRuns(jobID)
+		delete(s.runs, jobID)
+	}
+	for _, runs := range s.runs {
+		for _, run := range runs {
+			run.cancelled = true
+		}
+	}
+	s.mutex.Unlock()
+	s.stopAll()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("runs still in progress: %w", ctx.Err())
+	}
+
+	if closeErr := s.store.Close(); closeErr != nil {
+		s.logger.Errorf("Failed to close job store: %v", closeErr)
+	}
 	s.logger.Info("Scheduler stopped")
+	return err
+}
+
+// saveJob writes the current state of job to the store. It is called with
+// the mutex held, so that the store sees changes in order.
+func (s *Scheduler) saveJob(job *ScheduledJob) {
+	if err := s.store.SaveJob(job); err != nil {
+		s.logger.Errorf("Failed to persist job %s: %v", job.ID, err)
+	}
 }
 
 func (s *Scheduler) AddJob(job *ScheduledJob) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	// Validate cron expression
-	_, err := cron.ParseStandard(job.Schedule)
-	if err != nil {
-		return fmt.Errorf("invalid cron expression: %w", err)
+	// Validate schedule
+	if err := ValidateSchedule(job.Schedule, job.TimeZone); err != nil {
+		return err
+	}
+	if err := ValidateBlackouts(job.BlackoutWindows, job.BlackoutPolicy); err != nil {
+		return err
+	}
+	if err := ValidateOverlapPolicy(job.OverlapPolicy); err != nil {
+		return err
+	}
+	if err := ValidateRetry(job.Retry, job.PauseAfterFailures); err != nil {
+		return err
 	}
+	schedule, _ := parseSchedule(job.Schedule, jobLocation(job))
 
 	// Generate ID if not provided
 	if job.ID == "" {
@@ -114,17 +295,17 @@ func (s *Scheduler) AddJob(job *ScheduledJob) error {
 	}
 
 	// Add to cron scheduler
-	entryID, err := s.cron.AddFunc(job.Schedule, s.createJobFunction(job))
-	if err != nil {
-		return fmt.Errorf("failed to add job to cron: %w", err)
-	}
+	entryID := s.scheduleJob(job, schedule)
 
 	// Store job
 	s.jobs[job.ID] = job
-	s.jobEntries[job.ID] = entryID
 
-	// Calculate next run
-	s.updateNextRun(job)
+	if err := s.store.SaveJob(job); err != nil {
+		s.cron.Remove(entryID)
+		delete(s.jobs, job.ID)
+		delete(s.jobEntries, job.ID)
+		return fmt.Errorf("failed to persist job: %w", err)
+	}
 
 	s.logger.Infof("Scheduled job added: %s (%s) - Next run: %s", job.Name, job.ID, job.NextRun.Format(time.RFC3339))
 
@@ -140,11 +321,20 @@ func (s *Scheduler) RemoveJob(jobID string) error {
 		return fmt.Errorf("job not found: %s", jobID)
 	}
 
+	if err := s.store.DeleteJob(jobID); err != nil {
+		return fmt.Errorf("failed to delete job from store: %w", err)
+	}
+
 	// Remove from cron scheduler
 	if entryID, exists := s.jobEntries[jobID]; exists {
 		s.cron.Remove(entryID)
 		delete(s.jobEntries, jobID)
 	}
+	s.cancelDeferred(jobID)
+	s.dropQueued(jobID)
+	s.cancelRetry(jobID)
+	s.cancelRuns(jobID)
+	delete(s.runs, jobID)
 
 	// Remove from jobs map
 	delete(s.jobs, jobID)
@@ -173,9 +363,14 @@ func (s *Scheduler) PauseJob(jobID string) error {
 		delete(s.jobEntries, jobID)
 	}
 
+	s.cancelDeferred(jobID)
+	s.dropQueued(jobID)
+	s.cancelRetry(jobID)
+
 	job.Status = JobStatusPaused
 	job.UpdatedAt = time.Now()
 	job.NextRun = nil
+	s.saveJob(job)
 
 	s.logger.Infof("Scheduled job paused: %s (%s)", job.Name, jobID)
 
@@ -196,17 +391,17 @@ func (s *Scheduler) ResumeJob(jobID string) error {
 	}
 
 	// Add back to cron scheduler
-	entryID, err := s.cron.AddFunc(job.Schedule, s.createJobFunction(job))
-	if err != nil {
+	if err := ValidateSchedule(job.Schedule, job.TimeZone); err != nil {
 		return fmt.Errorf("failed to resume job: %w", err)
 	}
+	schedule, _ := parseSchedule(job.Schedule, jobLocation(job))
+	s.scheduleJob(job, schedule)
 
 	job.Status = JobStatusActive
 	job.UpdatedAt = time.Now()
-	s.jobEntries[jobID] = entryID
-
-	// Calculate next run
-	s.updateNextRun(job)
+	job.ConsecutiveFailures = 0
+	job.PausedReason = ""
+	s.saveJob(job)
 
 	s.logger.Infof("Scheduled job resumed: %s (%s) - Next run: %s", job.Name, jobID, job.NextRun.Format(time.RFC3339))
 
@@ -237,41 +432,283 @@ func (s *Scheduler) GetAllJobs() []*ScheduledJob {
 	return jobs
 }
 
-func (s *Scheduler) RunJobNow(jobID string) error {
-	job, err := s.GetJob(jobID)
-	if err != nil {
+// GetJobCopy returns a copy of a job that can be changed and passed to
+// UpdateJob. Its results and history are shared with the job and must not
+// be modified.
+func (s *Scheduler) GetJobCopy(jobID string) (*ScheduledJob, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	job, exists := s.jobs[jobID]
+	if !exists {
+		return nil, fmt.Errorf("job not found: %s", jobID)
+	}
+
+	copied := *job
+	return &copied, nil
+}
+
+// UpdateJob applies the definition of updated, a changed copy from
+// GetJobCopy, to the job with its ID, then reschedules and stores the job.
+// The status and run history stay with the job. Paused jobs stay paused.
+func (s *Scheduler) UpdateJob(updated *ScheduledJob) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	job, exists := s.jobs[updated.ID]
+	if !exists {
+		return fmt.Errorf("job not found: %s", updated.ID)
+	}
+
+	// Validate schedule
+	if err := ValidateSchedule(updated.Schedule, updated.TimeZone); err != nil {
+		return err
+	}
+	if err := ValidateBlackouts(updated.BlackoutWindows, updated.BlackoutPolicy); err != nil {
+		return err
+	}
+	if err := ValidateOverlapPolicy(updated.OverlapPolicy); err != nil {
+		return err
+	}
+	if err := ValidateRetry(updated.Retry, updated.PauseAfterFailures); err != nil {
+		return err
+	}
+
+	job.Name = updated.Name
+	job.Description = updated.Description
+	job.Schedule = updated.Schedule
+	job.URL = updated.URL
+	job.Options = updated.Options
+	job.ChangeDetection = updated.ChangeDetection
+	job.TimeZone = updated.TimeZone
+	job.BlackoutWindows = updated.BlackoutWindows
+	job.BlackoutPolicy = updated.BlackoutPolicy
+	job.OverlapPolicy = updated.OverlapPolicy
+	job.Retry = updated.Retry
+	job.PauseAfterFailures = updated.PauseAfterFailures
+
+	if entryID, exists := s.jobEntries[job.ID]; exists {
+		s.cron.Remove(entryID)
+		delete(s.jobEntries, job.ID)
+	}
+	s.cancelRetry(job.ID)
+	if err := s.addJobInternal(job); err != nil {
 		return err
 	}
+	job.UpdatedAt = time.Now()
+
+	if err := s.store.SaveJob(job); err != nil {
+		return fmt.Errorf("failed to persist job: %w", err)
+	}
 
-	// Run job in goroutine to avoid blocking
-	go s.executeJob(job)
+	s.logger.Infof("Scheduled job updated: %s (%s)", job.Name, job.ID)
 
 	return nil
 }
 
+// CancelJob cancels the runs of a job in progress and drops its queued
+// runs.
+func (s *Scheduler) CancelJob(jobID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	job, exists := s.jobs[jobID]
+	if !exists {
+		return fmt.Errorf("job not found: %s", jobID)
+	}
+
+	if s.inFlight[jobID] == 0 && s.pending[jobID] == nil && s.retries[jobID] == nil {
+		return ErrJobNotRunning
+	}
+	s.dropQueued(jobID)
+	s.cancelRetry(jobID)
+	s.cancelRuns(jobID)
+
+	s.logger.Infof("Scheduled job cancelled: %s (%s)", job.Name, jobID)
+
+	return nil
+}
+
+// RunJobNow runs a job right away, subject to its overlap policy and the
+// limit on concurrent runs.
+func (s *Scheduler) RunJobNow(jobID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	job, exists := s.jobs[jobID]
+	if !exists {
+		return fmt.Errorf("job not found: %s", jobID)
+	}
+
+	s.cancelRetry(jobID)
+	return s.dispatch(job, true)
+}
+
 func (s *Scheduler) SetCallbacks(onJobStart, onJobComplete, onJobError func(*JobResult)) {
 	s.onJobStart = onJobStart
 	s.onJobComplete = onJobComplete
 	s.onJobError = onJobError
 }
 
+// SetChangeCallback sets the callback for runs whose result changed.
+func (s *Scheduler) SetChangeCallback(onJobChanged func(*JobResult)) {
+	s.onJobChanged = onJobChanged
+}
+
+// GetJobChanges returns the change history of a job, newest first.
+func (s *Scheduler) GetJobChanges(jobID string, changedOnly bool) ([]*ChangeSet, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	job, exists := s.jobs[jobID]
+	if !exists {
+		return nil, fmt.Errorf("job not found: %s", jobID)
+	}
+
+	changes := make([]*ChangeSet, 0, len(job.Changes))
+	for i := len(job.Changes) - 1; i >= 0; i-- {
+		if changedOnly && !job.Changes[i].Changed {
+			continue
+		}
+		changes = append(changes, job.Changes[i])
+	}
+	return changes, nil
+}
+
 func (s *Scheduler) createJobFunction(job *ScheduledJob) func() {
 	return func() {
-		s.executeJob(job)
+		s.runScheduled(job)
+	}
+}
+
+// runScheduled runs a job at its scheduled time unless a blackout window
+// is in effect. Manual runs ignore blackout windows.
+func (s *Scheduler) runScheduled(job *ScheduledJob) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// A scheduled run replaces a pending retry of the previous one
+	s.cancelRetry(job.ID)
+	if !s.blackedOut(job) {
+		s.dispatch(job, false)
+	}
+}
+
+// blackedOut reports whether a blackout window keeps a scheduled run of job
+// from starting now, and if so skips or defers the run according to the
+// blackout policy of the job. It is called with the mutex held.
+func (s *Scheduler) blackedOut(job *ScheduledJob) bool {
+	now := time.Now()
+	until, blocked := blackoutEnd(job, now)
+	if !blocked {
+		return false
+	}
+
+	result := &JobResult{
+		JobID:     job.ID,
+		JobName:   job.Name,
+		Status:    JobStatusSkipped,
+		StartedAt: now,
+		EndedAt:   now,
+	}
+	switch {
+	case job.BlackoutPolicy == BlackoutDefer && job.DeferredUntil == nil:
+		result.Status = JobStatusDeferred
+		result.Reason = fmt.Sprintf("blackout window, deferred until %s", until.Format(time.RFC3339))
+		s.deferRun(job, until)
+	case job.BlackoutPolicy == BlackoutDefer:
+		result.Reason = fmt.Sprintf("blackout window, a run is already deferred until %s", job.DeferredUntil.Format(time.RFC3339))
+	default:
+		result.Reason = fmt.Sprintf("blackout window until %s", until.Format(time.RFC3339))
+	}
+	s.logger.Infof("Scheduled job %s: %s (%s) - %s", result.Status, job.Name, job.ID, result.Reason)
+
+	s.recordRun(job, result)
+	if result.Status == JobStatusSkipped && s.finishOneShot(job) {
+		job.Status = JobStatusComplete
+	} else {
+		s.updateNextRun(job)
+	}
+	s.saveJob(job)
+	return true
+}
+
+// deferRun runs job once at the given time. It is called with the mutex
+// held.
+func (s *Scheduler) deferRun(job *ScheduledJob, at time.Time) {
+	s.cancelDeferred(job.ID)
+	job.DeferredUntil = &at
+	s.deferred[job.ID] = time.AfterFunc(time.Until(at), func() {
+		s.mutex.Lock()
+		current, exists := s.jobs[job.ID]
+		if !exists || current != job || job.DeferredUntil == nil || !job.DeferredUntil.Equal(at) {
+			s.mutex.Unlock()
+			return
+		}
+		delete(s.deferred, job.ID)
+		job.DeferredUntil = nil
+		s.mutex.Unlock()
+
+		// Windows may have changed in the meantime
+		s.runScheduled(job)
+	})
+}
+
+// cancelDeferred drops the deferred run of a job. It is called with the
+// mutex held.
+func (s *Scheduler) cancelDeferred(jobID string) {
+	if timer, exists := s.deferred[jobID]; exists {
+		timer.Stop()
+		delete(s.deferred, jobID)
+	}
+	if job, exists := s.jobs[jobID]; exists {
+		job.DeferredUntil = nil
+	}
+}
+
+// recordRun adds a run to the history of a job. It is called with the
+// mutex held.
+func (s *Scheduler) recordRun(job *ScheduledJob, result *JobResult) {
+	run := *result
+	run.Data = nil
+	job.History = append(job.History, &run)
+	if len(job.History) > maxRunHistory {
+		job.History = job.History[len(job.History)-maxRunHistory:]
 	}
 }
 
 func (s *Scheduler) executeJob(job *ScheduledJob) {
+	// Take the settings of this run under the lock, UpdateJob may change
+	// them while it is in progress
 	s.mutex.Lock()
+	if s.jobs[job.ID] != job {
+		// Removed or replaced by an import before the run started
+		s.mutex.Unlock()
+		return
+	}
+	name, url, options, changeDetection := job.Name, job.URL, job.Options, job.ChangeDetection
 	job.Status = JobStatusRunning
 	job.UpdatedAt = time.Now()
+	attempt := s.attempts[job.ID] + 1
+	s.saveJob(job)
+
+	// Runs without a deadline of their own get the default one
+	timeout := defaultJobTimeout
+	if options != nil && options.Timeout > 0 {
+		timeout = options.Timeout
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, timeout)
+	defer cancel()
+	run := &activeRun{cancel: cancel}
+	s.runs[job.ID] = append(s.runs[job.ID], run)
 	s.mutex.Unlock()
 
 	startTime := time.Now()
 	result := &JobResult{
 		JobID:     job.ID,
-		JobName:   job.Name,
+		JobName:   name,
 		Status:    JobStatusRunning,
+		Attempt:   attempt,
 		StartedAt: startTime,
 	}
 
@@ -280,26 +717,49 @@ func (s *Scheduler) executeJob(job *ScheduledJob) {
 		s.onJobStart(result)
 	}
 
-	s.logger.Infof("Executing scheduled job: %s (%s)", job.Name, job.ID)
+	s.logger.Infof("Executing scheduled job: %s (%s)", name, job.ID)
 
 	// Execute scraping
-	ctx, cancel := context.WithTimeout(context.Background(), job.Options.Timeout)
-	defer cancel()
+	if options == nil {
+		options = &scraper.CrawlingOptions{}
+	}
+	if changeDetection != nil && len(changeDetection.IgnoreSelectors) > 0 {
+		withIgnored := *options
+		withIgnored.IgnoreSelectors = append(append([]string{}, options.IgnoreSelectors...), changeDetection.IgnoreSelectors...)
+		options = &withIgnored
+	}
 
-	data, err := s.scraper.ScrapeWebsiteWithOptions(ctx, job.URL, job.Options)
+	data, err := s.scraper.ScrapeWebsiteWithOptions(ctx, url, options)
 
 	endTime := time.Now()
 	duration := endTime.Sub(startTime)
 
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	s.untrackRun(job.ID, run)
 
 	// Update job statistics
 	job.LastRun = &endTime
 	job.RunCount++
 	job.UpdatedAt = endTime
 
-	if err != nil {
+	if err != nil && run.cancelled {
+		job.Status = JobStatusCancelled
+		result.Status = JobStatusCancelled
+		result.Error = "run cancelled"
+
+		s.logger.Infof("Scheduled job run cancelled: %s (%s) - Duration: %v", job.Name, job.ID, duration)
+
+		// Notify job error
+		result.EndedAt = endTime
+		result.Duration = duration
+		if s.onJobError != nil {
+			s.onJobError(result)
+		}
+	} else if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("deadline of %v exceeded: %w", timeout, err)
+		}
 		job.Status = JobStatusError
 		job.ErrorCount++
 		job.LastError = err.Error()
@@ -309,12 +769,25 @@ func (s *Scheduler) executeJob(job *ScheduledJob) {
 		s.logger.Errorf("Scheduled job failed: %s (%s) - Error: %v", job.Name, job.ID, err)
 
 		// Notify job error
+		result.EndedAt = endTime
+		result.Duration = duration
 		if s.onJobError != nil {
-			result.EndedAt = endTime
-			result.Duration = duration
 			s.onJobError(result)
 		}
 	} else {
+		// Compare with the previous result
+		if job.LastResult != nil {
+			changes := DiffResults(job.LastResult, data, job.ChangeDetection)
+			job.Changes = append(job.Changes, changes)
+			if len(job.Changes) > maxChangeHistory {
+				job.Changes = job.Changes[len(job.Changes)-maxChangeHistory:]
+			}
+			if changes.Changed {
+				job.LastChangedAt = &endTime
+			}
+			result.Changes = changes
+		}
+
 		job.Status = JobStatusComplete
 		job.LastError = ""
 		job.LastResult = data
@@ -331,15 +804,62 @@ func (s *Scheduler) executeJob(job *ScheduledJob) {
 		s.logger.Infof("Scheduled job completed: %s (%s) - Duration: %v", job.Name, job.ID, duration)
 
 		// Notify job completion
+		result.EndedAt = endTime
+		result.Duration = duration
 		if s.onJobComplete != nil {
-			result.EndedAt = endTime
-			result.Duration = duration
 			s.onJobComplete(result)
 		}
+		if result.Changes != nil && result.Changes.Changed && s.onJobChanged != nil {
+			s.onJobChanged(result)
+		}
+	}
+
+	// A job removed or replaced by an import while it ran is not stored
+	// or scheduled again
+	if s.jobs[job.ID] != job {
+		return
 	}
 
 	// Calculate next run
-	s.updateNextRun(job)
+	if !s.finishOneShot(job) {
+		s.updateNextRun(job)
+	}
+
+	switch result.Status {
+	case JobStatusComplete:
+		delete(s.attempts, job.ID)
+		job.ConsecutiveFailures = 0
+	case JobStatusError:
+		s.handleFailure(job, result, err)
+	}
+
+	s.recordRun(job, result)
+	s.saveJob(job)
+}
+
+// cancelRuns cancels the runs of a job in progress. It is called with the
+// mutex held.
+func (s *Scheduler) cancelRuns(jobID string) {
+	for _, run := range s.runs[jobID] {
+		run.cancelled = true
+		run.cancel()
+	}
+}
+
+// untrackRun forgets a finished run. It is called with the mutex held.
+func (s *Scheduler) untrackRun(jobID string, run *activeRun) {
+	runs := s.runs[jobID]
+	for i, r := range runs {
+		if r == run {
+			runs = append(runs[:i], runs[i+1:]...)
+			break
+		}
+	}
+	if len(runs) == 0 {
+		delete(s.runs, jobID)
+	} else {
+		s.runs[jobID] = runs
+	}
 }
 
 func (s *Scheduler) updateNextRun(job *ScheduledJob) {
@@ -347,7 +867,15 @@ func (s *Scheduler) updateNextRun(job *ScheduledJob) {
 	entries := s.cron.Entries()
 	for _, entry := range entries {
 		if entry.ID == s.jobEntries[job.ID] {
-			job.NextRun = &entry.Next
+			// Entries only get their next time once the cron is running
+			next := entry.Next
+			if next.IsZero() {
+				next = entry.Schedule.Next(time.Now())
+			}
+			job.NextRun = nil
+			if !next.IsZero() {
+				job.NextRun = &next
+			}
 			break
 		}
 	}
@@ -365,6 +893,11 @@ func (s *Scheduler) GetJobStats() map[string]interface{} {
 		"error_jobs":   0,
 		"total_runs":   0,
 		"total_errors": 0,
+		// Runs in progress and waiting under the concurrency limits
+		"running_runs":        s.active,
+		"queued_runs":         len(s.queue) + len(s.pending),
+		"queue":               s.queuedRuns(),
+		"max_concurrent_jobs": s.maxConcurrent,
 	}
 
 	for _, job := range s.jobs {
@@ -406,45 +939,120 @@ func (s *Scheduler) ImportJobs(data []byte) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	// API output redacts inline credentials, take them from the jobs
+	// being replaced
+	for _, job := range jobs {
+		existing, exists := s.jobs[job.ID]
+		if exists && job.Options != nil && job.Options.Auth != nil && existing.Options != nil {
+			job.Options.Auth.RestoreRedacted(existing.Options.Auth)
+		}
+		s.pauseUnusable(job, "import")
+	}
+
 	// Clear existing jobs
+	for jobID := range s.deferred {
+		s.cancelDeferred(jobID)
+	}
+	for jobID := range s.jobs {
+		s.dropQueued(jobID)
+		s.cancelRetry(jobID)
+		s.cancelRuns(jobID)
+		delete(s.runs, jobID)
+	}
 	s.jobs = make(map[string]*ScheduledJob)
 	s.jobEntries = make(map[string]cron.EntryID)
 
 	// Import new jobs
 	for _, job := range jobs {
+		job.DeferredUntil = nil
+		job.NextRetry = nil
 		if err := s.addJobInternal(job); err != nil {
 			s.logger.Errorf("Failed to import job %s: %v", job.ID, err)
 			continue
 		}
 	}
 
+	imported := make([]*ScheduledJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		imported = append(imported, job)
+	}
+	if err := s.store.ReplaceJobs(imported); err != nil {
+		return fmt.Errorf("failed to persist imported jobs: %w", err)
+	}
+
 	s.logger.Infof("Imported %d scheduled jobs", len(jobs))
 	return nil
 }
 
-func (s *Scheduler) addJobInternal(job *ScheduledJob) error {
-	// Validate cron expression
-	_, err := cron.ParseStandard(job.Schedule)
-	if err != nil {
-		return fmt.Errorf("invalid cron expression: %w", err)
+// pauseUnusable pauses a job whose options can't be used, such as redacted
+// credentials, until they are updated. It is called with the mutex held.
+func (s *Scheduler) pauseUnusable(job *ScheduledJob, after string) {
+	if job.Options == nil || job.Status == JobStatusPaused {
+		return
+	}
+	if err := job.Options.Validate(); err != nil {
+		job.Status = JobStatusPaused
+		job.NextRun = nil
+		job.LastError = "paused after " + after + ": " + err.Error()
+		s.logger.Warnf("Scheduled job %s (%s) paused: %v", job.Name, job.ID, err)
 	}
+}
 
-	// Add to cron scheduler
-	entryID, err := s.cron.AddFunc(job.Schedule, s.createJobFunction(job))
+func (s *Scheduler) addJobInternal(job *ScheduledJob) error {
+	// Validate schedule
+	schedule, err := parseSchedule(job.Schedule, jobLocation(job))
 	if err != nil {
-		return fmt.Errorf("failed to add job to cron: %w", err)
+		return fmt.Errorf("invalid schedule: %w", err)
 	}
 
 	// Store job
 	s.jobs[job.ID] = job
-	s.jobEntries[job.ID] = entryID
 
-	// Calculate next run
-	s.updateNextRun(job)
+	// Paused jobs are kept but not scheduled
+	if job.Status == JobStatusPaused {
+		job.NextRun = nil
+		return nil
+	}
+
+	// One-shot jobs whose time has passed are kept but not scheduled
+	if schedule.Next(time.Now()).IsZero() {
+		job.NextRun = nil
+		if job.LastRun == nil {
+			job.Status = JobStatusError
+			job.LastError = fmt.Sprintf("one-shot run at %s was missed", job.Schedule)
+		}
+		return nil
+	}
+
+	// Add to cron scheduler
+	s.scheduleJob(job, schedule)
 
 	return nil
 }
 
+// scheduleJob adds job to the cron scheduler and calculates its next run.
+func (s *Scheduler) scheduleJob(job *ScheduledJob, schedule cron.Schedule) cron.EntryID {
+	entryID := s.cron.Schedule(schedule, cron.FuncJob(s.createJobFunction(job)))
+	s.jobEntries[job.ID] = entryID
+	s.updateNextRun(job)
+	return entryID
+}
+
+// finishOneShot stops scheduling a one-shot job once its time has come.
+// Manual runs before that time don't count.
+func (s *Scheduler) finishOneShot(job *ScheduledJob) bool {
+	at, ok := parseOneShot(job.Schedule, jobLocation(job))
+	if !ok || time.Now().Before(at) {
+		return false
+	}
+	if entryID, exists := s.jobEntries[job.ID]; exists {
+		s.cron.Remove(entryID)
+		delete(s.jobEntries, job.ID)
+	}
+	job.NextRun = nil
+	return true
+}
+
 func generateJobID() string {
 	return fmt.Sprintf("job_%d", time.Now().UnixNano())
 }
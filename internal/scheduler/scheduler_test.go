@@ -0,0 +1,197 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"web-scraper-api/internal/scraper"
+)
+
+func runsInProgress(s *Scheduler, job *ScheduledJob) int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.runs[job.ID])
+}
+
+func jobName(s *Scheduler, job *ScheduledJob) string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return job.Name
+}
+
+func TestScheduler_CancelJob(t *testing.T) {
+	s, url, _ := newBlockingScheduler(t)
+	job := addBlockingJob(t, s, url, "")
+
+	if err := s.CancelJob(job.ID); !errors.Is(err, ErrJobNotRunning) {
+		t.Errorf("Expected ErrJobNotRunning, got %v", err)
+	}
+
+	if err := s.RunJobNow(job.ID); err != nil {
+		t.Fatalf("Failed to run job: %v", err)
+	}
+	waitFor(t, "the run to start", func() bool { return runsInProgress(s, job) == 1 })
+
+	if err := s.CancelJob(job.ID); err != nil {
+		t.Fatalf("Failed to cancel job: %v", err)
+	}
+	waitFor(t, "the run to end", func() bool { return runCount(s, job) == 1 })
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if job.Status != JobStatusCancelled {
+		t.Errorf("Expected status %s, got %s", JobStatusCancelled, job.Status)
+	}
+	if job.ErrorCount != 0 {
+		t.Errorf("Should not count a cancelled run as an error, got %d errors", job.ErrorCount)
+	}
+	if last := job.History[len(job.History)-1]; last.Status != JobStatusCancelled {
+		t.Errorf("Should record the cancelled run, got %s", last.Status)
+	}
+}
+
+func TestScheduler_DefaultDeadline(t *testing.T) {
+	s, url, release := newBlockingScheduler(t)
+	close(release)
+
+	job := addBlockingJob(t, s, url, "")
+	job.Options = &scraper.CrawlingOptions{}
+	s.executeJob(job)
+
+	if job.Status != JobStatusComplete {
+		t.Errorf("Should apply the default deadline to a zero timeout, got %s (%s)", job.Status, job.LastError)
+	}
+
+	s, url, _ = newBlockingScheduler(t)
+	job = addBlockingJob(t, s, url, "")
+	job.Options = &scraper.CrawlingOptions{Timeout: 50 * time.Millisecond}
+	s.executeJob(job)
+	if job.Status != JobStatusError {
+		t.Errorf("Expected the job to fail after its deadline, got %s", job.Status)
+	}
+}
+
+func TestScheduler_StopCancelsRuns(t *testing.T) {
+	s, url, _ := newBlockingScheduler(t)
+	job := addBlockingJob(t, s, url, "")
+
+	if err := s.RunJobNow(job.ID); err != nil {
+		t.Fatalf("Failed to run job: %v", err)
+	}
+	waitFor(t, "the run to start", func() bool { return runsInProgress(s, job) == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Fatalf("Should wait for the cancelled run, got %v", err)
+	}
+	if job.Status != JobStatusCancelled {
+		t.Errorf("Expected status %s, got %s", JobStatusCancelled, job.Status)
+	}
+	if err := s.RunJobNow(job.ID); !errors.Is(err, ErrSchedulerStopped) {
+		t.Errorf("Should not start runs after Stop, got %v", err)
+	}
+}
+
+func TestScheduler_UpdateJobDuringRun(t *testing.T) {
+	s, url, release := newBlockingScheduler(t)
+	job := addBlockingJob(t, s, url, "")
+
+	if err := s.RunJobNow(job.ID); err != nil {
+		t.Fatalf("Failed to run job: %v", err)
+	}
+	waitFor(t, "the run to start", func() bool { return runsInProgress(s, job) == 1 })
+
+	updated, err := s.GetJobCopy(job.ID)
+	if err != nil {
+		t.Fatalf("Failed to copy job: %v", err)
+	}
+	updated.Name = "renamed"
+	updated.OverlapPolicy = OverlapQueue
+	updated.Retry = &RetryPolicy{MaxAttempts: 2}
+	if name := jobName(s, job); name != "slow" {
+		t.Errorf("Changes to the copy should not reach the job before UpdateJob, got %q", name)
+	}
+
+	updated.OverlapPolicy = "sometimes"
+	if err := s.UpdateJob(updated); err == nil {
+		t.Error("Should reject an invalid update")
+	}
+	updated.OverlapPolicy = OverlapQueue
+	if err := s.UpdateJob(updated); err != nil {
+		t.Fatalf("Failed to update job: %v", err)
+	}
+
+	close(release)
+	waitFor(t, "the run to finish", func() bool { return runCount(s, job) == 1 })
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if job.Name != "renamed" || job.OverlapPolicy != OverlapQueue || job.Retry == nil {
+		t.Errorf("Should apply the update to the job, got %q and %q", job.Name, job.OverlapPolicy)
+	}
+	if s.jobs[job.ID] != job || len(job.History) != 1 {
+		t.Error("Should keep the job and its run history")
+	}
+}
+
+func TestScheduler_RemoveJobDuringRun(t *testing.T) {
+	s, url, release := newBlockingScheduler(t)
+	job := addBlockingJob(t, s, url, "")
+	job.Retry = &RetryPolicy{MaxAttempts: 2}
+
+	if err := s.RunJobNow(job.ID); err != nil {
+		t.Fatalf("Failed to run job: %v", err)
+	}
+	waitFor(t, "the run to start", func() bool { return runsInProgress(s, job) == 1 })
+
+	if err := s.RemoveJob(job.ID); err != nil {
+		t.Fatalf("Failed to remove job: %v", err)
+	}
+	close(release)
+	waitFor(t, "the run to finish", func() bool { return s.GetJobStats()["running_runs"] == 0 })
+
+	jobs, err := s.store.LoadJobs()
+	if err != nil {
+		t.Fatalf("Failed to load jobs: %v", err)
+	}
+	if len(jobs) != 0 {
+		t.Errorf("Should not store a job removed during its run, got %d jobs", len(jobs))
+	}
+	if len(s.retries) != 0 {
+		t.Error("Should not retry a removed job")
+	}
+}
+
+func TestScheduler_RemoveJobCancelsRuns(t *testing.T) {
+	s, url, _ := newBlockingScheduler(t)
+	job := addBlockingJob(t, s, url, "")
+	other := addBlockingJob(t, s, url, "")
+
+	for _, id := range []string{job.ID, other.ID} {
+		if err := s.RunJobNow(id); err != nil {
+			t.Fatalf("Failed to run job: %v", err)
+		}
+	}
+	waitFor(t, "the runs to start", func() bool {
+		return runsInProgress(s, job) == 1 && runsInProgress(s, other) == 1
+	})
+
+	if err := s.RemoveJob(job.ID); err != nil {
+		t.Fatalf("Failed to remove job: %v", err)
+	}
+	if runsInProgress(s, job) != 0 {
+		t.Error("Should stop tracking the runs of a removed job")
+	}
+	waitFor(t, "the removed job's run to end", func() bool { return runCount(s, job) == 1 })
+
+	if err := s.ImportJobs([]byte(`{}`)); err != nil {
+		t.Fatalf("Failed to import jobs: %v", err)
+	}
+	waitFor(t, "the replaced job's run to end", func() bool { return runCount(s, other) == 1 })
+	if jobStatus(s, other) != JobStatusCancelled {
+		t.Errorf("Expected status %s, got %s", JobStatusCancelled, jobStatus(s, other))
+	}
+}
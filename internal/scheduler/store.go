@@ -0,0 +1,245 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"web-scraper-api/internal/scraper"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// JobStore persists scheduled jobs together with their status and run
+// history.
+type JobStore interface {
+	// LoadJobs returns every stored job.
+	LoadJobs() ([]*ScheduledJob, error)
+	// SaveJob stores a job, replacing an older version with the same ID.
+	SaveJob(job *ScheduledJob) error
+	// DeleteJob removes a job. Deleting an unknown job is not an error.
+	DeleteJob(jobID string) error
+	// ReplaceJobs removes every stored job and stores jobs instead, in a
+	// single step.
+	ReplaceJobs(jobs []*ScheduledJob) error
+	Close() error
+}
+
+// storedJob is how a job is encoded in a store. The JSON of a job redacts
+// inline credentials for the API, so the store keeps them next to it.
+type storedJob struct {
+	Job  *ScheduledJob `json:"job"`
+	Auth *storedAuth   `json:"auth,omitempty"`
+}
+
+// storedAuth has the fields of scraper.AuthConfig without its redacting
+// MarshalJSON.
+type storedAuth scraper.AuthConfig
+
+func encodeJob(job *ScheduledJob) ([]byte, error) {
+	stored := storedJob{Job: job}
+	if job.Options != nil && job.Options.Auth != nil {
+		stored.Auth = (*storedAuth)(job.Options.Auth)
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job %s: %w", job.ID, err)
+	}
+	return data, nil
+}
+
+// decodeJob reads a job written by encodeJob, or a plain job as stored by
+// earlier versions.
+func decodeJob(id, data []byte) (*ScheduledJob, error) {
+	var stored storedJob
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse job %s: %w", id, err)
+	}
+	if stored.Job == nil {
+		var job ScheduledJob
+		if err := json.Unmarshal(data, &job); err != nil {
+			return nil, fmt.Errorf("failed to parse job %s: %w", id, err)
+		}
+		return &job, nil
+	}
+	if stored.Auth != nil && stored.Job.Options != nil {
+		stored.Job.Options.Auth = (*scraper.AuthConfig)(stored.Auth)
+	}
+	return stored.Job, nil
+}
+
+// memoryJobStore keeps jobs in memory only, for tests and when no store file
+// is configured.
+type memoryJobStore struct {
+	mu   sync.Mutex
+	jobs map[string][]byte
+}
+
+// NewMemoryJobStore creates a job store that does not survive restarts.
+func NewMemoryJobStore() JobStore {
+	return &memoryJobStore{jobs: make(map[string][]byte)}
+}
+
+func (m *memoryJobStore) LoadJobs() ([]*ScheduledJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make([]*ScheduledJob, 0, len(m.jobs))
+	for id, data := range m.jobs {
+		job, err := decodeJob([]byte(id), data)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	sortJobs(jobs)
+	return jobs, nil
+}
+
+// SaveJob stores an encoded copy so that later changes to job are only seen
+// once they are saved.
+func (m *memoryJobStore) SaveJob(job *ScheduledJob) error {
+	data, err := encodeJob(job)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[job.ID] = data
+	return nil
+}
+
+func (m *memoryJobStore) DeleteJob(jobID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.jobs, jobID)
+	return nil
+}
+
+func (m *memoryJobStore) ReplaceJobs(jobs []*ScheduledJob) error {
+	encoded := make(map[string][]byte, len(jobs))
+	for _, job := range jobs {
+		data, err := encodeJob(job)
+		if err != nil {
+			return err
+		}
+		encoded[job.ID] = data
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs = encoded
+	return nil
+}
+
+func (m *memoryJobStore) Close() error {
+	return nil
+}
+
+var jobsBucket = []byte("jobs")
+
+// boltJobStore keeps jobs in a bbolt database file. Every change is written
+// in its own transaction, so a crash leaves either the old or the new
+// version of a job.
+type boltJobStore struct {
+	db *bolt.DB
+}
+
+// NewFileJobStore opens or creates the job database at path.
+func NewFileJobStore(path string) (JobStore, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create job store directory: %w", err)
+		}
+	}
+
+	// A second process holding the file lock makes Open fail after the
+	// timeout instead of blocking forever
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job store %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(jobsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize job store: %w", err)
+	}
+	return &boltJobStore{db: db}, nil
+}
+
+func (b *boltJobStore) LoadJobs() ([]*ScheduledJob, error) {
+	var jobs []*ScheduledJob
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).ForEach(func(id, data []byte) error {
+			job, err := decodeJob(id, data)
+			if err != nil {
+				return err
+			}
+			jobs = append(jobs, job)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortJobs(jobs)
+	return jobs, nil
+}
+
+func (b *boltJobStore) SaveJob(job *ScheduledJob) error {
+	data, err := encodeJob(job)
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Put([]byte(job.ID), data)
+	})
+}
+
+func (b *boltJobStore) DeleteJob(jobID string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Delete([]byte(jobID))
+	})
+}
+
+func (b *boltJobStore) ReplaceJobs(jobs []*ScheduledJob) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(jobsBucket); err != nil {
+			return err
+		}
+		bucket, err := tx.CreateBucket(jobsBucket)
+		if err != nil {
+			return err
+		}
+		for _, job := range jobs {
+			data, err := encodeJob(job)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(job.ID), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *boltJobStore) Close() error {
+	return b.db.Close()
+}
+
+// sortJobs orders jobs by creation time so that they are scheduled in a
+// stable order.
+func sortJobs(jobs []*ScheduledJob) {
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+}
@@ -0,0 +1,231 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-scraper-api/internal/logger"
+	"web-scraper-api/internal/scraper"
+)
+
+func newTestJob(name string) *ScheduledJob {
+	return &ScheduledJob{
+		Name:     name,
+		Schedule: "@every 1h",
+		URL:      "https://example.com",
+		Options:  &scraper.CrawlingOptions{Timeout: 30 * time.Second},
+	}
+}
+
+func TestFileJobStore_SurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs", "scheduler.db")
+
+	store, err := NewFileJobStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	s := NewScheduler(logger.New("error"), nil, store)
+
+	kept, removed, paused := newTestJob("kept"), newTestJob("removed"), newTestJob("paused")
+	for _, job := range []*ScheduledJob{kept, removed, paused} {
+		if err := s.AddJob(job); err != nil {
+			t.Fatalf("Failed to add job: %v", err)
+		}
+	}
+	if err := s.RemoveJob(removed.ID); err != nil {
+		t.Fatalf("Failed to remove job: %v", err)
+	}
+	if err := s.PauseJob(paused.ID); err != nil {
+		t.Fatalf("Failed to pause job: %v", err)
+	}
+
+	// Simulate a run with history
+	s.mutex.Lock()
+	kept.RunCount = 3
+	kept.LastResult = &scraper.ScrapedData{URL: kept.URL, Title: "Example"}
+	kept.Results = []*scraper.ScrapedData{kept.LastResult}
+	kept.Changes = []*ChangeSet{{Changed: true, Score: 0.5}}
+	s.saveJob(kept)
+	s.mutex.Unlock()
+	s.Stop(context.Background())
+
+	store, err = NewFileJobStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	s = NewScheduler(logger.New("error"), nil, store)
+	defer s.Stop(context.Background())
+
+	jobs := s.GetAllJobs()
+	if len(jobs) != 2 {
+		t.Fatalf("Expected 2 jobs after restart, got %d", len(jobs))
+	}
+
+	loaded, err := s.GetJob(kept.ID)
+	if err != nil {
+		t.Fatalf("Should load job %s: %v", kept.ID, err)
+	}
+	if loaded.RunCount != 3 || loaded.LastResult == nil || loaded.LastResult.Title != "Example" ||
+		len(loaded.Results) != 1 || len(loaded.Changes) != 1 {
+		t.Errorf("Should keep the run history, got %+v", loaded)
+	}
+	if loaded.NextRun == nil || loaded.NextRun.IsZero() {
+		t.Error("Should schedule the loaded job")
+	}
+
+	loadedPaused, err := s.GetJob(paused.ID)
+	if err != nil {
+		t.Fatalf("Should load job %s: %v", paused.ID, err)
+	}
+	if loadedPaused.Status != JobStatusPaused || loadedPaused.NextRun != nil {
+		t.Errorf("Should keep the job paused, got status %s", loadedPaused.Status)
+	}
+	if _, exists := s.jobEntries[paused.ID]; exists {
+		t.Error("Should not schedule the paused job")
+	}
+}
+
+func TestScheduler_LoadResetsInterruptedRuns(t *testing.T) {
+	store := NewMemoryJobStore()
+	job := newTestJob("interrupted")
+	job.ID = "job_1"
+	job.Status = JobStatusRunning
+	if err := store.SaveJob(job); err != nil {
+		t.Fatalf("Failed to save job: %v", err)
+	}
+
+	s := NewScheduler(logger.New("error"), nil, store)
+	loaded, err := s.GetJob("job_1")
+	if err != nil {
+		t.Fatalf("Should load the job: %v", err)
+	}
+	if loaded.Status != JobStatusActive {
+		t.Errorf("Expected status %s, got %s", JobStatusActive, loaded.Status)
+	}
+}
+
+func TestFileJobStore_KeepsCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scheduler.db")
+	store, err := NewFileJobStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	s := NewScheduler(logger.New("error"), nil, store)
+
+	job := newTestJob("with credentials")
+	job.Options.Auth = &scraper.AuthConfig{Type: scraper.AuthTypeBasic, Username: "user", Password: "hunter2"}
+	if err := s.AddJob(job); err != nil {
+		t.Fatalf("Failed to add job: %v", err)
+	}
+	s.Stop(context.Background())
+
+	store, err = NewFileJobStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	s = NewScheduler(logger.New("error"), nil, store)
+	defer s.Stop(context.Background())
+
+	loaded, err := s.GetJob(job.ID)
+	if err != nil {
+		t.Fatalf("Should load the job: %v", err)
+	}
+	if loaded.Options.Auth.Password != "hunter2" {
+		t.Errorf("Should keep inline credentials across restarts, got %q", loaded.Options.Auth.Password)
+	}
+	if loaded.Status != JobStatusActive {
+		t.Errorf("Should keep the job active, got status %s (%s)", loaded.Status, loaded.LastError)
+	}
+	if data, _ := json.Marshal(loaded); strings.Contains(string(data), "hunter2") {
+		t.Error("Should still redact the credentials in the API output")
+	}
+}
+
+func TestScheduler_LoadPausesRedactedCredentials(t *testing.T) {
+	// Earlier versions stored the redacted API JSON of a job
+	job := newTestJob("with credentials")
+	job.ID = "job_1"
+	job.Options.Auth = &scraper.AuthConfig{Type: scraper.AuthTypeBasic, Username: "user", Password: "hunter2"}
+	data, err := json.Marshal(job)
+	if err != nil {
+		t.Fatalf("Failed to encode job: %v", err)
+	}
+	store := &memoryJobStore{jobs: map[string][]byte{job.ID: data}}
+
+	s := NewScheduler(logger.New("error"), nil, store)
+	loaded, err := s.GetJob("job_1")
+	if err != nil {
+		t.Fatalf("Should load the job: %v", err)
+	}
+	if loaded.Options.Auth.Password == "hunter2" {
+		t.Error("Should not have stored inline credentials")
+	}
+	if loaded.Status != JobStatusPaused || !strings.Contains(loaded.LastError, "paused after restart") {
+		t.Errorf("Should pause the job, got status %s and error %q", loaded.Status, loaded.LastError)
+	}
+}
+
+func TestScheduler_ImportRestoresCredentials(t *testing.T) {
+	s := NewScheduler(logger.New("error"), nil, nil)
+
+	job := newTestJob("with credentials")
+	job.Options.Auth = &scraper.AuthConfig{Type: scraper.AuthTypeBearer, Token: "token-1"}
+	if err := s.AddJob(job); err != nil {
+		t.Fatalf("Failed to add job: %v", err)
+	}
+	other := newTestJob("unknown")
+	other.ID = "job_unknown"
+	other.Options.Auth = &scraper.AuthConfig{Type: scraper.AuthTypeBearer, Token: "token-2"}
+
+	s.mutex.RLock()
+	exported, err := json.Marshal(map[string]*ScheduledJob{job.ID: job, other.ID: other})
+	s.mutex.RUnlock()
+	if err != nil {
+		t.Fatalf("Failed to export jobs: %v", err)
+	}
+	if strings.Contains(string(exported), "token-") {
+		t.Fatal("Export should redact inline credentials")
+	}
+	if err := s.ImportJobs(exported); err != nil {
+		t.Fatalf("Failed to import jobs: %v", err)
+	}
+
+	restored, _ := s.GetJob(job.ID)
+	if restored.Options.Auth.Token != "token-1" || restored.Status != JobStatusActive {
+		t.Errorf("Should restore the credentials of the replaced job, got %q and status %s", restored.Options.Auth.Token, restored.Status)
+	}
+	unknown, _ := s.GetJob(other.ID)
+	if unknown.Status != JobStatusPaused || !strings.Contains(unknown.LastError, "paused after import") {
+		t.Errorf("Should pause jobs whose credentials can't be restored, got status %s", unknown.Status)
+	}
+}
+
+func TestScheduler_ImportReplacesStoredJobs(t *testing.T) {
+	store := NewMemoryJobStore()
+	s := NewScheduler(logger.New("error"), nil, store)
+	if err := s.AddJob(newTestJob("old")); err != nil {
+		t.Fatalf("Failed to add job: %v", err)
+	}
+
+	imported := newTestJob("imported")
+	imported.ID = "job_imported"
+	data, err := json.Marshal(map[string]*ScheduledJob{imported.ID: imported})
+	if err != nil {
+		t.Fatalf("Failed to export job: %v", err)
+	}
+	if err := s.ImportJobs(data); err != nil {
+		t.Fatalf("Failed to import jobs: %v", err)
+	}
+
+	jobs, err := store.LoadJobs()
+	if err != nil {
+		t.Fatalf("Failed to load jobs: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != "job_imported" {
+		t.Errorf("Should only store the imported job, got %d jobs", len(jobs))
+	}
+}
@@ -0,0 +1,632 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+)
+
+// AuthType selects how requests are authenticated.
+type AuthType string
+
+const (
+	AuthTypeBasic  AuthType = "basic"
+	AuthTypeBearer AuthType = "bearer"
+	AuthTypeOAuth2 AuthType = "oauth2_client_credentials"
+	AuthTypeForm   AuthType = "form"
+)
+
+// secretPrefix marks credential values that name a secret in the secret
+// store, e.g. "secret:portal-password".
+const secretPrefix = "secret:"
+
+// redactedValue replaces inline credentials in JSON output.
+const redactedValue = "[REDACTED]"
+
+// tokenExpirySkew renews access tokens this long before they expire.
+const tokenExpirySkew = 30 * time.Second
+
+// ErrSecretNotFound is returned when a referenced secret does not exist.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// ErrSecretHostNotAllowed is returned when a referenced secret would be sent
+// to a host it is not bound to.
+var ErrSecretHostNotAllowed = errors.New("secret not allowed for host")
+
+// AuthConfig authenticates the requests of a scrape or crawl. Credentials are
+// only sent to the host of the scraped URL. Every credential value may
+// reference the secret store with "secret:<name>", as long as the secret is
+// bound to the host it goes to; inline values are redacted when the options
+// are written as JSON.
+type AuthConfig struct {
+	Type AuthType `json:"type"`
+
+	// Basic auth
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// Static bearer token
+	Token string `json:"token,omitempty"`
+
+	// OAuth2 client credentials; tokens are cached and renewed before they
+	// expire or after a 401 response
+	TokenURL     string   `json:"token_url,omitempty"`
+	ClientID     string   `json:"client_id,omitempty"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+
+	// Form login, run before the first request
+	Form *FormLogin `json:"form,omitempty"`
+}
+
+// FormLogin submits a login form and checks that the login succeeded. The
+// form's other inputs, e.g. CSRF tokens, are submitted with their values
+// from the login page.
+type FormLogin struct {
+	URL string `json:"url"`
+	// Form to submit, defaults to the first form with a password field
+	FormSelector string            `json:"form_selector,omitempty"`
+	Fields       map[string]string `json:"fields"`
+	// Success checks on the page after login. Without them the login
+	// succeeds when that page has no password field.
+	SuccessURL      string `json:"success_url,omitempty"`
+	SuccessSelector string `json:"success_selector,omitempty"`
+	FailureSelector string `json:"failure_selector,omitempty"`
+}
+
+// MarshalJSON redacts inline credentials so that they don't leak through
+// the API; secret references are kept.
+func (a AuthConfig) MarshalJSON() ([]byte, error) {
+	type plain AuthConfig
+	redacted := plain(a)
+	redacted.Password = redactCredential(a.Password)
+	redacted.Token = redactCredential(a.Token)
+	redacted.ClientSecret = redactCredential(a.ClientSecret)
+	if a.Form != nil {
+		form := *a.Form
+		form.Fields = make(map[string]string, len(a.Form.Fields))
+		for name, value := range a.Form.Fields {
+			form.Fields[name] = redactCredential(value)
+		}
+		redacted.Form = &form
+	}
+	return json.Marshal(redacted)
+}
+
+// RestoreRedacted replaces the redacted values of a config read from API
+// output with those of from, the config it was produced from. Values without
+// a counterpart in from stay redacted.
+func (a *AuthConfig) RestoreRedacted(from *AuthConfig) {
+	if from == nil || from.Type != a.Type {
+		return
+	}
+	restore := func(value *string, original string) {
+		if *value == redactedValue && original != "" {
+			*value = original
+		}
+	}
+	restore(&a.Password, from.Password)
+	restore(&a.Token, from.Token)
+	restore(&a.ClientSecret, from.ClientSecret)
+	if a.Form != nil && from.Form != nil {
+		for name, value := range a.Form.Fields {
+			restore(&value, from.Form.Fields[name])
+			a.Form.Fields[name] = value
+		}
+	}
+}
+
+func redactCredential(value string) string {
+	if value == "" || strings.HasPrefix(value, secretPrefix) {
+		return value
+	}
+	return redactedValue
+}
+
+// Validate checks that the fields required by the auth type are set.
+func (a *AuthConfig) Validate() error {
+	switch a.Type {
+	case AuthTypeBasic:
+		if a.Username == "" {
+			return fmt.Errorf("basic auth requires a username")
+		}
+	case AuthTypeBearer:
+		if a.Token == "" {
+			return fmt.Errorf("bearer auth requires a token")
+		}
+	case AuthTypeOAuth2:
+		if a.ClientID == "" || a.ClientSecret == "" {
+			return fmt.Errorf("oauth2 auth requires a client id and secret")
+		}
+		if u, err := url.Parse(a.TokenURL); err != nil || u.Host == "" {
+			return fmt.Errorf("oauth2 auth requires a valid token url")
+		}
+	case AuthTypeForm:
+		if a.Form == nil || len(a.Form.Fields) == 0 {
+			return fmt.Errorf("form auth requires form fields")
+		}
+		if u, err := url.Parse(a.Form.URL); err != nil || u.Host == "" {
+			return fmt.Errorf("form auth requires a valid login url")
+		}
+		for _, sel := range []string{a.Form.FormSelector, a.Form.SuccessSelector, a.Form.FailureSelector} {
+			if sel == "" {
+				continue
+			}
+			if _, err := cascadia.Compile(sel); err != nil {
+				return fmt.Errorf("invalid form auth selector %q: %w", sel, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown auth type %q", a.Type)
+	}
+
+	values := []string{a.Username, a.Password, a.Token, a.ClientID, a.ClientSecret}
+	if a.Form != nil {
+		for _, value := range a.Form.Fields {
+			values = append(values, value)
+		}
+	}
+	for _, value := range values {
+		// Redacted values come from API output posted back
+		if value == redactedValue {
+			return fmt.Errorf("redacted credentials cannot be used, reference a secret instead")
+		}
+		if name, ok := strings.CutPrefix(value, secretPrefix); ok && !namePattern.MatchString(name) {
+			return fmt.Errorf("invalid secret name %q", name)
+		}
+	}
+	return nil
+}
+
+// SecretStore resolves named credentials.
+type SecretStore interface {
+	Secret(name string) (string, error)
+	// Hosts returns the host patterns, like "example.com" or
+	// "*.example.com", that the secret may be sent to. Secrets without
+	// hosts are never sent.
+	Hosts(name string) ([]string, error)
+}
+
+// envSecretStore reads secrets from SCRAPER_SECRET_<NAME> environment
+// variables and, when a directory is configured, from files named after the
+// secret, as mounted by Docker and Kubernetes. The hosts of a secret are
+// listed in SCRAPER_SECRET_<NAME>_HOSTS or the file <name>.hosts, separated
+// by commas or whitespace.
+type envSecretStore struct {
+	dir string
+}
+
+func secretEnv(name string) string {
+	return "SCRAPER_SECRET_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+func (st *envSecretStore) Secret(name string) (string, error) {
+	if value, ok := os.LookupEnv(secretEnv(name)); ok {
+		return value, nil
+	}
+	if st.dir != "" {
+		data, err := os.ReadFile(filepath.Join(st.dir, name))
+		if err == nil {
+			return strings.TrimRight(string(data), "\r\n"), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+}
+
+func (st *envSecretStore) Hosts(name string) ([]string, error) {
+	value, ok := os.LookupEnv(secretEnv(name) + "_HOSTS")
+	if !ok && st.dir != "" {
+		data, err := os.ReadFile(filepath.Join(st.dir, name+".hosts"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		value = string(data)
+	}
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	}), nil
+}
+
+// SetSecretStore replaces the store that secret references are resolved
+// from.
+func (s *Service) SetSecretStore(store SecretStore) {
+	s.secrets = store
+}
+
+// checkSecretHost refuses value when it references a secret that is not
+// bound to host. Inline values are not checked.
+func (s *Service) checkSecretHost(value, host string) error {
+	name, ok := strings.CutPrefix(value, secretPrefix)
+	if !ok {
+		return nil
+	}
+	patterns, err := s.secrets.Hosts(name)
+	if err != nil {
+		return err
+	}
+	for i, pattern := range patterns {
+		patterns[i] = normalizeHostPattern(pattern)
+	}
+	if host == "" || !matchHostPatterns(normalizeHostPattern(host), patterns) {
+		return fmt.Errorf("%w: %s cannot be sent to %q", ErrSecretHostNotAllowed, name, host)
+	}
+	return nil
+}
+
+// resolveAuth returns a copy of the auth config with the secret references
+// replaced by their values. Each secret must be bound to the host it is sent
+// to: the target host for basic and bearer credentials, the token URL host
+// for client credentials and the login URL host for form fields.
+func (s *Service) resolveAuth(auth *AuthConfig, target *url.URL) (*AuthConfig, error) {
+	resolved := *auth
+	if auth.Form != nil {
+		form := *auth.Form
+		form.Fields = make(map[string]string, len(auth.Form.Fields))
+		for name, value := range auth.Form.Fields {
+			form.Fields[name] = value
+		}
+		resolved.Form = &form
+	}
+
+	resolve := func(value, host string) (string, error) {
+		name, ok := strings.CutPrefix(value, secretPrefix)
+		if !ok {
+			return value, nil
+		}
+		if err := s.checkSecretHost(value, host); err != nil {
+			return "", err
+		}
+		return s.secrets.Secret(name)
+	}
+	hostOf := func(rawURL string) string {
+		if u, err := url.Parse(rawURL); err == nil {
+			return u.Hostname()
+		}
+		return ""
+	}
+
+	for _, value := range []*string{&resolved.Username, &resolved.Password, &resolved.Token} {
+		v, err := resolve(*value, target.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		*value = v
+	}
+	for _, value := range []*string{&resolved.ClientID, &resolved.ClientSecret} {
+		v, err := resolve(*value, hostOf(auth.TokenURL))
+		if err != nil {
+			return nil, err
+		}
+		*value = v
+	}
+	if resolved.Form != nil {
+		loginHost := hostOf(auth.Form.URL)
+		for name, value := range resolved.Form.Fields {
+			v, err := resolve(value, loginHost)
+			if err != nil {
+				return nil, err
+			}
+			resolved.Form.Fields[name] = v
+		}
+	}
+	return &resolved, nil
+}
+
+// authState is the resolved auth config of a scrape or crawl.
+type authState struct {
+	config *AuthConfig
+	host   string
+}
+
+type authStateKey struct{}
+
+func authStateFrom(ctx context.Context) *authState {
+	state, _ := ctx.Value(authStateKey{}).(*authState)
+	return state
+}
+
+// withAuth resolves the auth config of the options and attaches it to ctx.
+// Form logins are performed right away, their session cookies go to the
+// cookie jar in ctx.
+func (s *Service) withAuth(ctx context.Context, target string, options *CrawlingOptions) (context.Context, error) {
+	if options.Auth == nil || authStateFrom(ctx) != nil {
+		return ctx, nil
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return ctx, &ScrapeError{Kind: ErrorKindPolicy, URL: target, Err: errors.New("invalid URL")}
+	}
+	config, err := s.resolveAuth(options.Auth, u)
+	if err != nil {
+		return ctx, &ScrapeError{Kind: ErrorKindAuth, URL: target, Err: err}
+	}
+
+	if config.Type == AuthTypeForm {
+		if err := s.formLogin(ctx, config.Form, options); err != nil {
+			return ctx, err
+		}
+	}
+
+	return context.WithValue(ctx, authStateKey{}, &authState{config: config, host: u.Host}), nil
+}
+
+// applyAuth sets the credentials of the auth config in ctx on req.
+func (s *Service) applyAuth(ctx context.Context, req *http.Request) error {
+	state := authStateFrom(ctx)
+	if state == nil || req.URL.Host != state.host {
+		return nil
+	}
+
+	switch state.config.Type {
+	case AuthTypeBasic:
+		req.SetBasicAuth(state.config.Username, state.config.Password)
+	case AuthTypeBearer:
+		req.Header.Set("Authorization", "Bearer "+state.config.Token)
+	case AuthTypeOAuth2:
+		token, err := s.tokens.token(ctx, s, state.config)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// invalidateAuth drops the cached access token of the auth config in ctx.
+func (s *Service) invalidateAuth(ctx context.Context) {
+	if state := authStateFrom(ctx); state != nil && state.config.Type == AuthTypeOAuth2 {
+		s.tokens.invalidate(state.config)
+	}
+}
+
+type accessToken struct {
+	value  string
+	expiry time.Time
+}
+
+// tokenCache shares OAuth2 access tokens between requests.
+type tokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]accessToken
+}
+
+func newTokenCache() *tokenCache {
+	return &tokenCache{tokens: make(map[string]accessToken)}
+}
+
+func tokenKey(config *AuthConfig) string {
+	return config.TokenURL + "|" + config.ClientID + "|" + strings.Join(config.Scopes, " ")
+}
+
+// token returns a valid access token, requesting a new one if needed.
+func (c *tokenCache) token(ctx context.Context, s *Service, config *AuthConfig) (string, error) {
+	key := tokenKey(config)
+
+	c.mu.Lock()
+	cached, ok := c.tokens[key]
+	c.mu.Unlock()
+	if ok && (cached.expiry.IsZero() || time.Now().Add(tokenExpirySkew).Before(cached.expiry)) {
+		return cached.value, nil
+	}
+
+	token, err := s.requestToken(ctx, config)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.tokens[key] = token
+	c.mu.Unlock()
+	return token.value, nil
+}
+
+func (c *tokenCache) invalidate(config *AuthConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.tokens, tokenKey(config))
+}
+
+// requestToken performs the OAuth2 client credentials grant.
+func (s *Service) requestToken(ctx context.Context, config *AuthConfig) (accessToken, error) {
+	if err := s.checkURL(config.TokenURL); err != nil {
+		return accessToken{}, err
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(config.Scopes) > 0 {
+		form.Set("scope", strings.Join(config.Scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return accessToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(config.ClientID), url.QueryEscape(config.ClientSecret))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return accessToken{}, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return accessToken{}, fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return accessToken{}, fmt.Errorf("invalid token response: %w", err)
+	}
+	if body.AccessToken == "" {
+		return accessToken{}, fmt.Errorf("token response has no access token")
+	}
+
+	token := accessToken{value: body.AccessToken}
+	if seconds, err := body.ExpiresIn.Int64(); err == nil && seconds > 0 {
+		token.expiry = time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+	return token, nil
+}
+
+// formLogin loads the login page, submits its form with the configured
+// fields and checks the resulting page.
+func (s *Service) formLogin(ctx context.Context, login *FormLogin, options *CrawlingOptions) error {
+	page, pageURL, _, err := s.fetchDocument(ctx, login.URL, nil, options)
+	if err != nil {
+		return err
+	}
+
+	form := page.Find("form:has(input[type=password])").First()
+	if login.FormSelector != "" {
+		form = page.Find(login.FormSelector).First()
+	}
+	if form.Length() == 0 {
+		return &ScrapeError{Kind: ErrorKindAuth, URL: login.URL, Err: errors.New("login form not found")}
+	}
+
+	values := formValues(form)
+	for name, value := range login.Fields {
+		values.Set(name, value)
+	}
+
+	action := pageURL
+	if href, ok := form.Attr("action"); ok && strings.TrimSpace(href) != "" {
+		if action, err = pageURL.Parse(strings.TrimSpace(href)); err != nil {
+			return &ScrapeError{Kind: ErrorKindAuth, URL: login.URL, Err: fmt.Errorf("invalid form action: %w", err)}
+		}
+	}
+
+	// The form may post elsewhere than the login URL; the secrets in its
+	// fields have to be bound to that host as well
+	if options.Auth != nil && options.Auth.Form != nil {
+		for _, value := range options.Auth.Form.Fields {
+			if err := s.checkSecretHost(value, action.Hostname()); err != nil {
+				return &ScrapeError{Kind: ErrorKindAuth, URL: login.URL, Err: err}
+			}
+		}
+	}
+
+	var result *goquery.Document
+	var resultURL *url.URL
+	var status int
+	if method, _ := form.Attr("method"); strings.EqualFold(method, http.MethodGet) {
+		target := *action
+		target.RawQuery = values.Encode()
+		result, resultURL, status, err = s.fetchDocument(ctx, target.String(), nil, options)
+	} else {
+		result, resultURL, status, err = s.fetchDocument(ctx, action.String(), values, options)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := checkLogin(login, result, resultURL, status); err != nil {
+		return &ScrapeError{Kind: ErrorKindAuth, URL: login.URL, StatusCode: status, Err: err}
+	}
+	s.logger.Infof("Logged in at %s", login.URL)
+	return nil
+}
+
+// formValues returns the values a browser would submit for form without
+// user input.
+func formValues(form *goquery.Selection) url.Values {
+	values := url.Values{}
+	form.Find("input[name], select[name], textarea[name]").Each(func(_ int, field *goquery.Selection) {
+		name, _ := field.Attr("name")
+		switch goquery.NodeName(field) {
+		case "select":
+			option := field.Find("option[selected]").First()
+			if option.Length() == 0 {
+				option = field.Find("option").First()
+			}
+			if value, ok := option.Attr("value"); ok {
+				values.Add(name, value)
+			} else if option.Length() > 0 {
+				values.Add(name, strings.TrimSpace(option.Text()))
+			}
+		case "textarea":
+			values.Add(name, field.Text())
+		default:
+			switch strings.ToLower(field.AttrOr("type", "text")) {
+			case "submit", "button", "image", "reset", "file":
+				return
+			case "checkbox", "radio":
+				if _, checked := field.Attr("checked"); !checked {
+					return
+				}
+				values.Add(name, field.AttrOr("value", "on"))
+			default:
+				values.Add(name, field.AttrOr("value", ""))
+			}
+		}
+	})
+	return values
+}
+
+func checkLogin(login *FormLogin, page *goquery.Document, pageURL *url.URL, status int) error {
+	if status >= 400 {
+		return fmt.Errorf("login returned status %d", status)
+	}
+	if login.SuccessURL != "" && !strings.Contains(pageURL.String(), login.SuccessURL) {
+		return fmt.Errorf("login ended at %s", pageURL.Redacted())
+	}
+	if login.FailureSelector != "" && page.Find(login.FailureSelector).Length() > 0 {
+		return errors.New("login failure marker found")
+	}
+	if login.SuccessSelector != "" {
+		if page.Find(login.SuccessSelector).Length() == 0 {
+			return errors.New("login success marker not found")
+		}
+		return nil
+	}
+	if login.SuccessURL == "" && page.Find("input[type=password]").Length() > 0 {
+		return errors.New("login form still present")
+	}
+	return nil
+}
+
+// fetchDocument performs a single request and parses the response as HTML.
+func (s *Service) fetchDocument(ctx context.Context, rawURL string, form url.Values, options *CrawlingOptions) (*goquery.Document, *url.URL, int, error) {
+	fetched, err := s.fetchOnce(ctx, rawURL, form, options)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	resp := fetched.resp
+	defer fetched.release(resp)
+	defer resp.Body.Close()
+
+	body, err := newLimitedBody(resp, s.limitsFor(options))
+	if err != nil {
+		return nil, nil, 0, &ScrapeError{Kind: ErrorKindParse, URL: rawURL, Err: err}
+	}
+	decoded, _, err := decodeBody(body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, nil, 0, &ScrapeError{Kind: ErrorKindParse, URL: rawURL, Err: err}
+	}
+	doc, err := goquery.NewDocumentFromReader(decoded)
+	if err != nil {
+		return nil, nil, 0, &ScrapeError{Kind: ErrorKindParse, URL: rawURL, Err: err}
+	}
+	return doc, resp.Request.URL, resp.StatusCode, nil
+}
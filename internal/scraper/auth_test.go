@@ -0,0 +1,211 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func newAuthTestServer(tokenRequests *int32) *httptest.Server {
+	page := func(w http.ResponseWriter, title, body string) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><head><title>%s</title></head><body>%s</body></html>", title, body)
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/basic":
+			if user, pass, ok := r.BasicAuth(); !ok || user != "alice" || pass != "hunter2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			page(w, "basic ok", "")
+		case "/token":
+			id, secret, _ := r.BasicAuth()
+			if r.Method != http.MethodPost || r.FormValue("grant_type") != "client_credentials" || id != "client" || secret != "s3cret" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			n := atomic.AddInt32(tokenRequests, 1)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"access_token": "tok-%d", "token_type": "bearer", "expires_in": 3600}`, n)
+		case "/api":
+			if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer tok-") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			page(w, r.Header.Get("Authorization"), "")
+		case "/login":
+			if r.Method == http.MethodPost {
+				if r.FormValue("csrf") != "xyz" || r.FormValue("user") != "alice" || r.FormValue("pass") != "hunter2" {
+					page(w, "login", `<form method="post"><input type="password" name="pass"></form><p class="error">Wrong password</p>`)
+					return
+				}
+				http.SetCookie(w, &http.Cookie{Name: "sid", Value: "logged-in", Path: "/"})
+				http.Redirect(w, r, "/account", http.StatusSeeOther)
+				return
+			}
+			page(w, "login", `<form action="/login" method="post">
+				<input type="hidden" name="csrf" value="xyz">
+				<input type="text" name="user">
+				<input type="password" name="pass">
+				<input type="submit" name="go" value="Sign in">
+			</form>`)
+		case "/account":
+			if c, err := r.Cookie("sid"); err != nil || c.Value != "logged-in" {
+				http.Redirect(w, r, "/login", http.StatusFound)
+				return
+			}
+			page(w, "account", `<a class="logout" href="/logout">Logout</a>`)
+		}
+	}))
+}
+
+func TestScrape_Auth(t *testing.T) {
+	var tokenRequests int32
+	server := newAuthTestServer(&tokenRequests)
+	defer server.Close()
+
+	t.Setenv("SCRAPER_SECRET_PORTAL_PASSWORD", "hunter2")
+	t.Setenv("SCRAPER_SECRET_PORTAL_PASSWORD_HOSTS", "127.0.0.1")
+	t.Setenv("SCRAPER_SECRET_MISSING_HOSTS", "127.0.0.1")
+	service := newTestService()
+	scrape := func(path string, auth *AuthConfig) (*ScrapedData, error) {
+		options := &CrawlingOptions{Auth: auth}
+		if err := options.Validate(); err != nil {
+			t.Fatalf("%s: options should be valid: %v", path, err)
+		}
+		return service.ScrapeWebsiteWithOptions(context.Background(), server.URL+path, options)
+	}
+
+	// Basic auth with the password from the secret store
+	data, err := scrape("/basic", &AuthConfig{Type: AuthTypeBasic, Username: "alice", Password: "secret:portal-password"})
+	if err != nil || data.Title != "basic ok" {
+		t.Errorf("Basic auth should succeed, got %v %v", data, err)
+	}
+
+	// OAuth2 client credentials, the token is cached between requests
+	oauth := &AuthConfig{Type: AuthTypeOAuth2, TokenURL: server.URL + "/token", ClientID: "client", ClientSecret: "s3cret"}
+	for i := 0; i < 2; i++ {
+		data, err = scrape("/api", oauth)
+		if err != nil || data.Title != "Bearer tok-1" {
+			t.Fatalf("OAuth2 auth should succeed, got %v %v", data, err)
+		}
+	}
+	if tokenRequests != 1 {
+		t.Errorf("Should reuse the access token, got %d token requests", tokenRequests)
+	}
+
+	// Form login with a CSRF token
+	login := &AuthConfig{Type: AuthTypeForm, Form: &FormLogin{
+		URL:             server.URL + "/login",
+		Fields:          map[string]string{"user": "alice", "pass": "secret:portal-password"},
+		SuccessSelector: "a.logout",
+	}}
+	data, err = scrape("/account", login)
+	if err != nil || data.Title != "account" {
+		t.Errorf("Form login should succeed, got %v %v", data, err)
+	}
+
+	login.Form.Fields["pass"] = "wrong"
+	_, err = scrape("/account", login)
+	if ErrorKindOf(err) != ErrorKindAuth {
+		t.Errorf("A failed login should return an auth error, got %v", err)
+	}
+
+	_, err = scrape("/basic", &AuthConfig{Type: AuthTypeBasic, Username: "alice", Password: "secret:missing"})
+	if ErrorKindOf(err) != ErrorKindAuth {
+		t.Errorf("A missing secret should return an auth error, got %v", err)
+	}
+}
+
+func TestScrape_SecretBoundToHosts(t *testing.T) {
+	var authorized int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" || r.FormValue("pass") != "" {
+			atomic.AddInt32(&authorized, 1)
+		}
+		// The login form posts to the same server under another host name
+		_, port, _ := net.SplitHostPort(r.Host)
+		fmt.Fprintf(w, `<html><head><title>ok</title></head><body>
+			<form method="post" action="http://localhost:%s/login"><input name="user"><input type="password" name="pass"></form>
+		</body></html>`, port)
+	}))
+	defer server.Close()
+
+	t.Setenv("SCRAPER_SECRET_API_TOKEN", "tok")
+	t.Setenv("SCRAPER_SECRET_API_TOKEN_HOSTS", "api.example.com, *.example.org")
+	service := newTestService()
+
+	for _, auth := range []*AuthConfig{
+		{Type: AuthTypeBearer, Token: "secret:api-token"},
+		{Type: AuthTypeOAuth2, TokenURL: server.URL + "/token", ClientID: "client", ClientSecret: "secret:api-token"},
+		{Type: AuthTypeForm, Form: &FormLogin{URL: server.URL + "/login", Fields: map[string]string{"pass": "secret:api-token"}}},
+	} {
+		_, err := service.ScrapeWebsiteWithOptions(context.Background(), server.URL, &CrawlingOptions{Auth: auth})
+		if !errors.Is(err, ErrSecretHostNotAllowed) {
+			t.Errorf("%s: should refuse a secret for a host outside its list, got %v", auth.Type, err)
+		}
+	}
+
+	// The host of the form action is checked as well
+	t.Setenv("SCRAPER_SECRET_API_TOKEN_HOSTS", "127.0.0.1")
+	login := &AuthConfig{Type: AuthTypeForm, Form: &FormLogin{URL: server.URL + "/login", Fields: map[string]string{"pass": "secret:api-token"}}}
+	if _, err := service.ScrapeWebsiteWithOptions(context.Background(), server.URL, &CrawlingOptions{Auth: login}); !errors.Is(err, ErrSecretHostNotAllowed) {
+		t.Errorf("Should refuse to post a secret to another host than the login URL, got %v", err)
+	}
+
+	if n := atomic.LoadInt32(&authorized); n != 0 {
+		t.Errorf("Should not send the secret, got %d requests with credentials", n)
+	}
+
+	// Unbound secrets are never sent
+	t.Setenv("SCRAPER_SECRET_API_TOKEN_HOSTS", "")
+	if err := service.checkSecretHost("secret:api-token", "api.example.com"); !errors.Is(err, ErrSecretHostNotAllowed) {
+		t.Errorf("Should refuse a secret without hosts, got %v", err)
+	}
+
+	// Hosts can also be listed next to the secret file
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "portal.hosts"), []byte("portal.example.com\n*.portal.example.com\n"), 0o600)
+	store := &envSecretStore{dir: dir}
+	if hosts, err := store.Hosts("portal"); err != nil || len(hosts) != 2 || hosts[1] != "*.portal.example.com" {
+		t.Errorf("Should read the hosts file, got %v %v", hosts, err)
+	}
+}
+
+func TestAuthConfig_RedactsInlineCredentials(t *testing.T) {
+	options := &CrawlingOptions{Auth: &AuthConfig{
+		Type:     AuthTypeBasic,
+		Username: "alice",
+		Password: "hunter2",
+		Form:     &FormLogin{Fields: map[string]string{"pass": "hunter2", "otp": "secret:otp"}},
+	}}
+
+	data, err := json.Marshal(options)
+	if err != nil {
+		t.Fatalf("Should marshal the options: %v", err)
+	}
+	if strings.Contains(string(data), "hunter2") {
+		t.Errorf("Inline credentials should be redacted, got %s", data)
+	}
+	if !strings.Contains(string(data), `"secret:otp"`) || !strings.Contains(string(data), `"alice"`) {
+		t.Errorf("Secret references and usernames should be kept, got %s", data)
+	}
+	if options.Auth.Password != "hunter2" {
+		t.Error("Redaction should not modify the options")
+	}
+
+	if err := (&AuthConfig{Type: "digest"}).Validate(); err == nil {
+		t.Error("Should reject unknown auth types")
+	}
+}
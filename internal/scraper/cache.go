@@ -0,0 +1,416 @@
+package scraper
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"web-scraper-api/internal/config"
+)
+
+// Default cache limits
+const (
+	defaultCacheTTL     = 24 * time.Hour
+	defaultCacheMaxSize = 100 << 20
+)
+
+// CacheMode controls how a request uses the response cache.
+type CacheMode string
+
+const (
+	// CacheModeDefault revalidates cached responses with conditional
+	// requests and stores new responses
+	CacheModeDefault CacheMode = ""
+	// CacheModePreferCache uses a cached response without contacting the
+	// origin and only fetches on a miss
+	CacheModePreferCache CacheMode = "prefer_cache"
+	// CacheModeCacheOnly never contacts the origin, e.g. to re-run
+	// extraction rules against previously fetched pages
+	CacheModeCacheOnly CacheMode = "cache_only"
+	// CacheModeBypass neither reads nor writes the cache
+	CacheModeBypass CacheMode = "bypass"
+)
+
+// CacheStatus tells whether a page was served from the cache.
+type CacheStatus string
+
+const (
+	CacheStatusMiss CacheStatus = "miss"
+	// The origin answered 304 Not Modified, the page is unchanged
+	CacheStatusRevalidated CacheStatus = "revalidated"
+	// Served from the cache without a request
+	CacheStatusHit CacheStatus = "hit"
+)
+
+// ErrNotCached is returned in cache_only mode when a URL is not cached.
+var ErrNotCached = errors.New("response not cached")
+
+func (m CacheMode) valid() bool {
+	switch m {
+	case CacheModeDefault, CacheModePreferCache, CacheModeCacheOnly, CacheModeBypass:
+		return true
+	}
+	return false
+}
+
+// cacheEntry is a stored response: its final URL, headers and the body
+// after content decoding.
+type cacheEntry struct {
+	URL        string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	StoredAt   time.Time
+}
+
+func (e *cacheEntry) size() int64 {
+	size := int64(len(e.URL) + len(e.Body))
+	for key, values := range e.Header {
+		for _, value := range values {
+			size += int64(len(key) + len(value))
+		}
+	}
+	return size
+}
+
+// conditional returns a copy of options whose headers revalidate the entry.
+func (e *cacheEntry) conditional(options *CrawlingOptions) *CrawlingOptions {
+	etag, lastModified := e.Header.Get("ETag"), e.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return options
+	}
+
+	conditional := *options
+	conditional.Headers = make(map[string]string, len(options.Headers)+2)
+	for key, value := range options.Headers {
+		conditional.Headers[key] = value
+	}
+	if etag != "" {
+		conditional.Headers["If-None-Match"] = etag
+	}
+	if lastModified != "" {
+		conditional.Headers["If-Modified-Since"] = lastModified
+	}
+	return &conditional
+}
+
+// revalidate refreshes the entry after a 304 response, which may carry
+// updated validators.
+func (e *cacheEntry) revalidate(header http.Header) {
+	for _, key := range []string{"ETag", "Last-Modified", "Cache-Control", "Expires"} {
+		if value := header.Get(key); value != "" {
+			e.Header.Set(key, value)
+		}
+	}
+	e.StoredAt = time.Now()
+}
+
+// body returns the entry as a page body.
+func (e *cacheEntry) body() *pageBody {
+	u, _ := url.Parse(e.URL)
+	return &pageBody{url: u, header: e.Header, body: e.Body}
+}
+
+// cachedData starts the scraped data of a page served from the cache.
+func cachedData(requestedURL string, entry *cacheEntry, status CacheStatus) *ScrapedData {
+	data := &ScrapedData{
+		URL:          entry.URL,
+		Status:       ScrapeStatusOK,
+		StatusCode:   entry.StatusCode,
+		ScrapedAt:    time.Now(),
+		MetaTags:     make(map[string]string),
+		Headers:      make(map[string]string),
+		CustomData:   make(map[string]string),
+		RequestedURL: requestedURL,
+		Cache:        status,
+	}
+	for key, values := range entry.Header {
+		data.Headers[key] = values[0]
+	}
+	return data
+}
+
+// cacheItem is the index record of an entry. Entries of a disk cache are
+// kept in files and only loaded on lookup.
+type cacheItem struct {
+	key      string
+	size     int64
+	storedAt time.Time
+	entry    *cacheEntry
+	elem     *list.Element
+}
+
+// responseCache stores responses by URL, in memory or in a directory, and
+// evicts the least recently used entries beyond its size limit.
+type responseCache struct {
+	mu      sync.Mutex
+	dir     string
+	ttl     time.Duration
+	maxSize int64
+	size    int64
+	items   map[string]*cacheItem
+	lru     *list.List
+}
+
+// newResponseCache creates the cache configured in cfg. It returns nil when
+// caching is disabled.
+func newResponseCache(cfg config.ScrapingConfig) (*responseCache, error) {
+	if !cfg.CacheEnabled {
+		return nil, nil
+	}
+
+	cache := &responseCache{
+		dir:     cfg.CacheDir,
+		ttl:     time.Duration(cfg.CacheTTL) * time.Second,
+		maxSize: defaultCacheMaxSize,
+		items:   make(map[string]*cacheItem),
+		lru:     list.New(),
+	}
+	if cache.ttl <= 0 {
+		cache.ttl = defaultCacheTTL
+	}
+
+	var err error
+	if cfg.CacheMaxSize != "" {
+		n, parseErr := config.ParseSize(cfg.CacheMaxSize)
+		if parseErr != nil || n == 0 {
+			err = fmt.Errorf("invalid cache size limit %q, using default", cfg.CacheMaxSize)
+		} else {
+			cache.maxSize = n
+		}
+	}
+
+	if cache.dir != "" {
+		if mkErr := os.MkdirAll(cache.dir, 0o700); mkErr != nil {
+			return nil, fmt.Errorf("failed to create cache directory: %w", mkErr)
+		}
+		cache.loadIndex()
+	}
+	return cache, err
+}
+
+// loadIndex indexes the entries left in the cache directory by a previous
+// run, oldest first.
+func (c *responseCache) loadIndex() {
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+
+	var items []*cacheItem
+	for _, file := range files {
+		key, ok := strings.CutSuffix(file.Name(), ".cache")
+		if !ok {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		items = append(items, &cacheItem{key: key, size: info.Size(), storedAt: info.ModTime()})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].storedAt.Before(items[j].storedAt)
+	})
+
+	for _, item := range items {
+		c.insert(item)
+	}
+	c.evict()
+}
+
+// cacheKey identifies the cached response of a URL. Requests made with
+// different sessions, credentials, headers, cookies, proxies or user agents
+// don't share entries.
+// The credentials are those resolved for the request in ctx, so requests
+// whose secret references resolve to different values don't share either.
+func cacheKey(ctx context.Context, rawURL string, options *CrawlingOptions) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n%s\n%s\n", rawURL, options.Session, options.Proxy, userAgentFor(options))
+
+	auth := options.Auth
+	if state := authStateFrom(ctx); state != nil {
+		auth = state.config
+	}
+	if auth != nil {
+		// Without the redaction of AuthConfig.MarshalJSON
+		type plain AuthConfig
+		credentials, _ := json.Marshal(plain(*auth))
+		hash.Write(credentials)
+	}
+	hash.Write([]byte("\n"))
+
+	names := make([]string, 0, len(options.Headers))
+	for name := range options.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(hash, "header %s: %s\n", strings.ToLower(name), options.Headers[name])
+	}
+	for _, cookie := range options.Cookies {
+		fmt.Fprintf(hash, "cookie %s=%s; %s; %s\n", cookie.Name, cookie.Value, cookie.Domain, cookie.Path)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// get returns the entry for key if it is still within the TTL.
+func (c *responseCache) get(key string) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	if time.Since(item.storedAt) > c.ttl {
+		c.remove(item)
+		return nil
+	}
+
+	c.lru.MoveToBack(item.elem)
+	if item.entry == nil {
+		entry, err := c.read(key)
+		if err != nil {
+			c.remove(item)
+			return nil
+		}
+		return entry
+	}
+
+	// Callers may update the headers of the entry they get
+	entry := *item.entry
+	entry.Header = item.entry.Header.Clone()
+	return &entry
+}
+
+// put stores an entry, replacing an older one for key.
+func (c *responseCache) put(key string, entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if old, ok := c.items[key]; ok {
+		c.remove(old)
+	}
+
+	item := &cacheItem{key: key, size: entry.size(), storedAt: entry.StoredAt}
+	if item.size > c.maxSize {
+		return
+	}
+	if c.dir == "" {
+		item.entry = entry
+	} else if err := c.write(key, entry); err != nil {
+		return
+	}
+	c.insert(item)
+	c.evict()
+}
+
+// purge removes every entry.
+func (c *responseCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, item := range c.items {
+		c.remove(item)
+	}
+}
+
+func (c *responseCache) insert(item *cacheItem) {
+	item.elem = c.lru.PushBack(item)
+	c.items[item.key] = item
+	c.size += item.size
+}
+
+func (c *responseCache) remove(item *cacheItem) {
+	c.lru.Remove(item.elem)
+	delete(c.items, item.key)
+	c.size -= item.size
+	if c.dir != "" {
+		os.Remove(c.path(item.key))
+	}
+}
+
+// evict removes the least recently used entries until the cache fits its
+// size limit.
+func (c *responseCache) evict() {
+	for c.size > c.maxSize && c.lru.Len() > 0 {
+		c.remove(c.lru.Front().Value.(*cacheItem))
+	}
+}
+
+func (c *responseCache) path(key string) string {
+	return filepath.Join(c.dir, key+".cache")
+}
+
+func (c *responseCache) read(key string) (*cacheEntry, error) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, err
+	}
+	var entry cacheEntry
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (c *responseCache) write(key string, entry *cacheEntry) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(entry); err != nil {
+		return err
+	}
+	tmp := c.path(key) + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path(key))
+}
+
+// cacheable reports whether a response may be stored.
+func cacheable(resp *http.Response, truncated bool) bool {
+	if resp.StatusCode != http.StatusOK || resp.Request.Method != http.MethodGet || truncated {
+		return false
+	}
+	return !strings.Contains(strings.ToLower(resp.Header.Get("Cache-Control")), "no-store")
+}
+
+// lookupCache returns the cached entry for a request, or nil when the cache
+// is disabled or bypassed.
+func (s *Service) lookupCache(ctx context.Context, rawURL string, options *CrawlingOptions) *cacheEntry {
+	if s.cache == nil || options.CacheMode == CacheModeBypass {
+		return nil
+	}
+	return s.cache.get(cacheKey(ctx, rawURL, options))
+}
+
+// storeCache stores a response for a request unless the cache is disabled
+// or bypassed.
+func (s *Service) storeCache(ctx context.Context, rawURL string, options *CrawlingOptions, entry *cacheEntry) {
+	if s.cache == nil || options.CacheMode == CacheModeBypass {
+		return
+	}
+	s.cache.put(cacheKey(ctx, rawURL, options), entry)
+}
+
+// PurgeCache removes every cached response.
+func (s *Service) PurgeCache() {
+	if s.cache != nil {
+		s.cache.purge()
+	}
+}
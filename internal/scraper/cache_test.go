@@ -0,0 +1,165 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"web-scraper-api/internal/config"
+	"web-scraper-api/internal/logger"
+)
+
+func newCacheTestService(dir, maxSize string) *Service {
+	return NewServiceWithConfig(&config.Config{
+		Scraping: config.ScrapingConfig{
+			AllowPrivateNetworks: true,
+			CacheEnabled:         true,
+			CacheDir:             dir,
+			CacheMaxSize:         maxSize,
+		},
+	}, logger.New("error"))
+}
+
+func TestScrape_ConditionalRequests(t *testing.T) {
+	var full, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(&full, 1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><title>Prices</title></head><body><span class="price">9.99</span></body></html>`)
+	}))
+	defer server.Close()
+
+	service := newCacheTestService("", "")
+	ctx := context.Background()
+
+	data, err := service.ScrapeWebsiteWithOptions(ctx, server.URL, &CrawlingOptions{})
+	if err != nil || data.Cache != CacheStatusMiss {
+		t.Fatalf("First scrape should miss the cache, got %v %v", data, err)
+	}
+
+	data, err = service.ScrapeWebsiteWithOptions(ctx, server.URL, &CrawlingOptions{})
+	if err != nil {
+		t.Fatalf("Should not return an error: %v", err)
+	}
+	if data.Cache != CacheStatusRevalidated || data.Title != "Prices" || data.StatusCode != http.StatusOK {
+		t.Errorf("A 304 should return the cached page, got %s %q %d", data.Cache, data.Title, data.StatusCode)
+	}
+	if full != 1 || notModified != 1 {
+		t.Errorf("Should send a conditional request, got %d full and %d not modified responses", full, notModified)
+	}
+
+	// New extraction rules run against the cached page without a request
+	data, err = service.ScrapeWebsiteWithOptions(ctx, server.URL, &CrawlingOptions{
+		CacheMode:       CacheModeCacheOnly,
+		ExtractionRules: []ExtractionField{{Name: "price", Selector: ".price", Type: TypeFloat}},
+	})
+	if err != nil || data.Cache != CacheStatusHit || data.ExtractedData["price"] != 9.99 {
+		t.Errorf("Should extract from the cached page, got %v %v", data, err)
+	}
+	if full+notModified != 2 {
+		t.Error("cache_only should not contact the origin")
+	}
+
+	_, err = service.ScrapeWebsiteWithOptions(ctx, server.URL+"/other", &CrawlingOptions{CacheMode: CacheModeCacheOnly})
+	if !errors.Is(err, ErrNotCached) {
+		t.Errorf("Should fail for uncached URLs in cache_only mode, got %v", err)
+	}
+
+	// Bypassing the cache fetches the full page
+	data, _ = service.ScrapeWebsiteWithOptions(ctx, server.URL, &CrawlingOptions{CacheMode: CacheModeBypass})
+	if data.Cache != "" || full != 2 {
+		t.Errorf("bypass should not use the cache, got %q with %d full responses", data.Cache, full)
+	}
+}
+
+func TestResponseCache_DiskAndSizeLimit(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/large" {
+			fmt.Fprintf(w, "<html><body>%s</body></html>", strings.Repeat("x", 4096))
+			return
+		}
+		fmt.Fprintf(w, "<html><head><title>%s</title></head></html>", r.URL.Path)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	ctx := context.Background()
+	service := newCacheTestService(dir, "2KB")
+	for _, path := range []string{"/a", "/large"} {
+		if _, err := service.ScrapeWebsiteWithOptions(ctx, server.URL+path, &CrawlingOptions{}); err != nil {
+			t.Fatalf("%s: should not return an error: %v", path, err)
+		}
+	}
+
+	// A new service, as after a restart, serves the page from disk
+	service = newCacheTestService(dir, "2KB")
+	data, err := service.ScrapeWebsiteWithOptions(ctx, server.URL+"/a", &CrawlingOptions{CacheMode: CacheModeCacheOnly})
+	if err != nil || data.Title != "/a" {
+		t.Errorf("Should load cached pages from disk, got %v %v", data, err)
+	}
+	if _, err := service.ScrapeWebsiteWithOptions(ctx, server.URL+"/large", &CrawlingOptions{CacheMode: CacheModeCacheOnly}); !errors.Is(err, ErrNotCached) {
+		t.Errorf("Responses above the size limit should not be cached, got %v", err)
+	}
+
+	service.PurgeCache()
+	if _, err := service.ScrapeWebsiteWithOptions(ctx, server.URL+"/a", &CrawlingOptions{CacheMode: CacheModeCacheOnly}); !errors.Is(err, ErrNotCached) {
+		t.Errorf("Purged pages should not be served, got %v", err)
+	}
+}
+
+func TestCacheKey_SeparatesCallers(t *testing.T) {
+	ctx := context.Background()
+	url := "https://example.com/account"
+	key := func(options *CrawlingOptions) string { return cacheKey(ctx, url, options) }
+
+	anonymous := key(&CrawlingOptions{})
+	alice := key(&CrawlingOptions{Auth: &AuthConfig{Type: AuthTypeBearer, Token: "alice-token"}})
+	bob := key(&CrawlingOptions{Auth: &AuthConfig{Type: AuthTypeBearer, Token: "bob-token"}})
+	if alice == bob || alice == anonymous {
+		t.Error("Requests with different bearer tokens should not share entries")
+	}
+
+	withHeader := key(&CrawlingOptions{Headers: map[string]string{"Authorization": "Bearer x", "Accept": "text/html"}})
+	if withHeader == anonymous {
+		t.Error("Requests with an Authorization header should not share entries with anonymous ones")
+	}
+	if withHeader != key(&CrawlingOptions{Headers: map[string]string{"Accept": "text/html", "Authorization": "Bearer x"}}) {
+		t.Error("The key should not depend on the order of the headers")
+	}
+	if key(&CrawlingOptions{Cookies: []Cookie{{Name: "sid", Value: "1"}}}) == key(&CrawlingOptions{Cookies: []Cookie{{Name: "sid", Value: "2"}}}) {
+		t.Error("Requests with different cookies should not share entries")
+	}
+	if key(&CrawlingOptions{Proxy: "http://de.proxy.test:8080"}) == key(&CrawlingOptions{Proxy: "http://us.proxy.test:8080"}) {
+		t.Error("Requests through different proxies should not share entries")
+	}
+	if key(&CrawlingOptions{UserAgent: "mobile"}) == anonymous {
+		t.Error("Requests with different user agents should not share entries")
+	}
+	if key(&CrawlingOptions{UserAgent: defaultUserAgent}) != anonymous {
+		t.Error("Requests with the default user agent should share entries")
+	}
+
+	// Secret references are keyed by the values they resolve to
+	ref := &CrawlingOptions{Auth: &AuthConfig{Type: AuthTypeBearer, Token: "secret:api"}}
+	resolved := func(token string) string {
+		state := &authState{config: &AuthConfig{Type: AuthTypeBearer, Token: token}, host: "example.com"}
+		return cacheKey(context.WithValue(ctx, authStateKey{}, state), url, ref)
+	}
+	if resolved("one") == resolved("two") {
+		t.Error("Secret references resolving to different tokens should not share entries")
+	}
+}
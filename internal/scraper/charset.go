@@ -0,0 +1,34 @@
+package scraper
+
+import (
+	"bufio"
+	"io"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// charsetSniffLen is how much of the body is inspected for a BOM and
+// <meta charset> or http-equiv declarations, as in the HTML spec prescan.
+const charsetSniffLen = 1024
+
+// decodeBody detects the character encoding of a page from the BOM, the
+// Content-Type header and <meta> declarations, in that order of precedence,
+// and returns a reader transcoding the body to UTF-8 along with the name of
+// the detected encoding. Undeclared non-UTF-8 content falls back to
+// windows-1252.
+func decodeBody(body io.Reader, contentType string) (io.Reader, string, error) {
+	br := bufio.NewReaderSize(body, charsetSniffLen)
+	head, err := br.Peek(charsetSniffLen)
+	if err != nil && err != io.EOF {
+		return nil, "", err
+	}
+
+	enc, name, _ := charset.DetermineEncoding(head, contentType)
+	if name == "utf-8" {
+		// Drop a UTF-8 byte order mark so it does not end up in the text
+		return transform.NewReader(br, unicode.UTF8BOM.NewDecoder()), name, nil
+	}
+	return transform.NewReader(br, enc.NewDecoder()), name, nil
+}
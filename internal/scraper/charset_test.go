@@ -0,0 +1,83 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func mustEncode(t *testing.T, enc encoding.Encoding, s string) []byte {
+	t.Helper()
+	b, err := enc.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatalf("Failed to encode test page: %v", err)
+	}
+	return b
+}
+
+func TestScrape_DecodesCharsets(t *testing.T) {
+	pages := map[string]struct {
+		contentType string
+		body        []byte
+	}{
+		"/header": {
+			contentType: "text/html; charset=windows-1252",
+			body:        mustEncode(t, charmap.Windows1252, "<html><head><title>Café Müller</title></head></html>"),
+		},
+		"/meta": {
+			contentType: "text/html",
+			body:        mustEncode(t, japanese.ShiftJIS, `<html><head><meta charset="Shift_JIS"><title>東京の天気</title></head></html>`),
+		},
+		"/http-equiv": {
+			contentType: "text/html",
+			body:        mustEncode(t, simplifiedchinese.GBK, `<html><head><meta http-equiv="Content-Type" content="text/html; charset=gbk"><title>北京新闻</title></head></html>`),
+		},
+		"/latin2": {
+			contentType: "text/html; charset=iso-8859-2",
+			body:        mustEncode(t, charmap.ISO8859_2, "<html><head><title>Łódź</title></head></html>"),
+		},
+		"/bom": {
+			contentType: "text/html; charset=iso-8859-1",
+			body:        append([]byte("\xef\xbb\xbf"), "<html><head><title>naïve</title></head></html>"...),
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := pages[r.URL.Path]
+		w.Header().Set("Content-Type", page.contentType)
+		w.Write(page.body)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path     string
+		title    string
+		encoding string
+	}{
+		{"/header", "Café Müller", "windows-1252"},
+		{"/meta", "東京の天気", "shift_jis"},
+		{"/http-equiv", "北京新闻", "gbk"},
+		{"/latin2", "Łódź", "iso-8859-2"},
+		{"/bom", "naïve", "utf-8"},
+	}
+
+	service := newTestService()
+	for _, tt := range tests {
+		data, err := service.ScrapeWebsiteWithOptions(context.Background(), server.URL+tt.path, &CrawlingOptions{})
+		if err != nil {
+			t.Fatalf("%s: should not return an error: %v", tt.path, err)
+		}
+		if data.Title != tt.title {
+			t.Errorf("%s: expected title %q, got %q", tt.path, tt.title, data.Title)
+		}
+		if data.Encoding != tt.encoding {
+			t.Errorf("%s: expected encoding %q, got %q", tt.path, tt.encoding, data.Encoding)
+		}
+	}
+}
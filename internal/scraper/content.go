@@ -0,0 +1,234 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/ledongthuc/pdf"
+)
+
+// ContentHandler processes responses of a non-HTML media type. HTML is
+// always parsed by the scraper itself.
+type ContentHandler interface {
+	// Match reports whether the handler accepts the media type, e.g.
+	// "application/json"
+	Match(mediaType string) bool
+	// Handle parses the body and fills data, usually Content, Title and
+	// Text. It returns the scope extraction rules are evaluated against,
+	// or nil if the content does not support extraction rules.
+	Handle(body []byte, contentType string, data *ScrapedData) (interface{}, error)
+}
+
+// RegisterContentHandler adds a handler for non-HTML content. Handlers
+// registered later take precedence over earlier ones and the built-in
+// handlers. It must not be called while scrapes are running.
+func (s *Service) RegisterContentHandler(handler ContentHandler) {
+	s.contentHandlers = append([]ContentHandler{handler}, s.contentHandlers...)
+}
+
+// defaultContentHandlers returns the built-in JSON, XML/feed, plain text
+// and PDF handlers.
+func defaultContentHandlers() []ContentHandler {
+	return []ContentHandler{jsonHandler{}, xmlHandler{}, textHandler{}, pdfHandler{}}
+}
+
+func (s *Service) contentHandler(mediaType string) ContentHandler {
+	for _, handler := range s.contentHandlers {
+		if handler.Match(mediaType) {
+			return handler
+		}
+	}
+	return nil
+}
+
+// sniffMediaType returns the media type of a response from its
+// Content-Type header, falling back to content sniffing when the header is
+// missing or invalid. The returned reader yields the full body.
+func sniffMediaType(contentType string, body io.Reader) (io.Reader, string, error) {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return body, strings.ToLower(mediaType), nil
+	}
+
+	br := bufio.NewReaderSize(body, 512)
+	head, err := br.Peek(512)
+	if err != nil && err != io.EOF {
+		return nil, "", err
+	}
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	return br, mediaType, nil
+}
+
+// isHTMLMediaType reports whether the media type is parsed as HTML.
+func isHTMLMediaType(mediaType string) bool {
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// handleContent runs the content handler for a non-HTML response and applies
+// the extraction rules to its result. Responses without a handler get the
+// unsupported_content_type status.
+func (s *Service) handleContent(body io.Reader, mediaType, contentType string, data *ScrapedData, options *CrawlingOptions) error {
+	handler := s.contentHandler(mediaType)
+	if handler == nil {
+		s.logger.Infof("Skipping %s: unsupported content type %q", data.URL, mediaType)
+		data.Status = ScrapeStatusUnsupportedContentType
+		return nil
+	}
+
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	scope, err := handler.Handle(raw, contentType, data)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s content: %w", mediaType, err)
+	}
+
+	if scope != nil && len(options.ExtractionRules) > 0 {
+		e := newExtractor()
+		data.ExtractedData = e.extract(scope, options.ExtractionRules)
+		data.ExtractionErrors = e.errors
+	}
+	return nil
+}
+
+// decodeText transcodes a text body to UTF-8 using the declared charset.
+func decodeText(body []byte, contentType string, data *ScrapedData) (string, error) {
+	r, encoding, err := decodeBody(bytes.NewReader(body), contentType)
+	if err != nil {
+		return "", err
+	}
+	text, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	data.Encoding = encoding
+	return string(text), nil
+}
+
+// jsonHandler decodes JSON documents; extraction rules use "jsonpath:"
+// selectors. The decoded document is the content payload.
+type jsonHandler struct{}
+
+func (jsonHandler) Match(mediaType string) bool {
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func (jsonHandler) Handle(body []byte, contentType string, data *ScrapedData) (interface{}, error) {
+	text, err := decodeText(body, contentType, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return nil, err
+	}
+	data.Content = value
+	return jsonValue{value}, nil
+}
+
+// XMLContent is the content payload for XML documents that are not feeds.
+type XMLContent struct {
+	Root      string `json:"root"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// xmlHandler parses XML documents; extraction rules use "xpath:" selectors.
+// RSS and Atom feeds are detected by their root element and normalized into
+// a Feed payload.
+type xmlHandler struct{}
+
+func (xmlHandler) Match(mediaType string) bool {
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+func (xmlHandler) Handle(body []byte, contentType string, data *ScrapedData) (interface{}, error) {
+	doc, err := xmlquery.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	root := xmlRootElement(doc)
+	if root == nil {
+		return nil, fmt.Errorf("document has no root element")
+	}
+
+	if feed := parseFeed(root); feed != nil {
+		data.Content = feed
+		data.Title = feed.Title
+		data.Description = feed.Description
+		for _, item := range feed.Items {
+			if item.Link != "" {
+				data.Links = append(data.Links, item.Link)
+			}
+		}
+	} else {
+		data.Content = &XMLContent{Root: root.Data, Namespace: root.NamespaceURI}
+	}
+
+	data.Text = strings.TrimSpace(doc.InnerText())
+	return doc, nil
+}
+
+// textHandler passes plain text through; extraction rules use "regex:"
+// selectors.
+type textHandler struct{}
+
+func (textHandler) Match(mediaType string) bool {
+	return mediaType == "text/plain"
+}
+
+func (textHandler) Handle(body []byte, contentType string, data *ScrapedData) (interface{}, error) {
+	text, err := decodeText(body, contentType, data)
+	if err != nil {
+		return nil, err
+	}
+	data.Text = text
+	return textValue(text), nil
+}
+
+// PDFContent is the content payload for PDF documents.
+type PDFContent struct {
+	Pages int `json:"pages"`
+}
+
+// pdfHandler extracts the plain text of PDF documents; extraction rules use
+// "regex:" selectors.
+type pdfHandler struct{}
+
+func (pdfHandler) Match(mediaType string) bool {
+	return mediaType == "application/pdf"
+}
+
+func (pdfHandler) Handle(body []byte, contentType string, data *ScrapedData) (scope interface{}, err error) {
+	// The PDF reader panics on some malformed documents
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, err
+	}
+	plain, err := reader.GetPlainText()
+	if err != nil {
+		return nil, err
+	}
+	text, err := io.ReadAll(plain)
+	if err != nil {
+		return nil, err
+	}
+
+	data.Content = &PDFContent{Pages: reader.NumPage()}
+	data.Text = strings.TrimSpace(string(text))
+	return textValue(data.Text), nil
+}
@@ -0,0 +1,200 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testRSSFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel>
+  <title>Example News</title>
+  <link>https://example.com/</link>
+  <description>Latest posts</description>
+  <item>
+    <title>First post</title>
+    <link>https://example.com/first</link>
+    <guid>post-1</guid>
+    <pubDate>Mon, 02 Mar 2026 10:00:00 +0000</pubDate>
+    <dc:creator>Jane</dc:creator>
+    <category>go</category>
+  </item>
+  <item>
+    <title>Second post</title>
+    <link>https://example.com/second</link>
+  </item>
+</channel>
+</rss>`
+
+const testAtomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Example Atom</title>
+  <link href="https://example.com/"/>
+  <updated>2026-03-02T10:00:00Z</updated>
+  <entry>
+    <id>urn:uuid:1</id>
+    <title>Atom entry</title>
+    <link rel="alternate" href="https://example.com/atom-entry"/>
+    <published>2026-03-01T08:00:00Z</published>
+    <author><name>John</name></author>
+    <category term="news"/>
+  </entry>
+</feed>`
+
+// testPDF builds a minimal one-page PDF showing text.
+func testPDF(text string) []byte {
+	stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func newContentTestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			fmt.Fprint(w, `{"store": {"name": "ACME", "products": [{"name": "Widget", "price": 9.5}, {"name": "Gadget", "price": 12}]}}`)
+		case "/rss":
+			w.Header().Set("Content-Type", "application/rss+xml")
+			fmt.Fprint(w, testRSSFeed)
+		case "/atom":
+			// Feeds served as generic XML are detected by their root element
+			w.Header().Set("Content-Type", "text/xml")
+			fmt.Fprint(w, testAtomFeed)
+		case "/catalog.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprint(w, `<catalog><book id="b1"><title>Go</title></book><book id="b2"><title>XML</title></book></catalog>`)
+		case "/notes.txt":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprint(w, "Order: 1234\nOrder: 5678\n")
+		case "/doc.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write(testPDF("Invoice 42"))
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG\r\n\x1a\n"))
+		}
+	}))
+}
+
+func TestScrape_ContentHandlers(t *testing.T) {
+	server := newContentTestServer()
+	defer server.Close()
+
+	service := newTestService()
+	scrape := func(path string, rules ...ExtractionField) *ScrapedData {
+		t.Helper()
+		data, err := service.ScrapeWebsiteWithOptions(context.Background(), server.URL+path, &CrawlingOptions{ExtractionRules: rules})
+		if err != nil {
+			t.Fatalf("%s: should not return an error: %v", path, err)
+		}
+		return data
+	}
+
+	// JSON with JSONPath extraction
+	data := scrape("/api",
+		ExtractionField{Name: "store", Selector: "jsonpath:$.store.name"},
+		ExtractionField{Name: "prices", Selector: "jsonpath:$.store.products[*].price", Multiple: true},
+		ExtractionField{Name: "products", Selector: "jsonpath:$.store.products[*]", Multiple: true, Fields: []ExtractionField{
+			{Name: "name", Selector: "jsonpath:$.name", Transforms: []Transform{{Op: TransformUpper}}},
+		}},
+	)
+	if data.ContentType != "application/json" || data.Content == nil {
+		t.Errorf("Unexpected JSON content: %s %v", data.ContentType, data.Content)
+	}
+	if data.ExtractedData["store"] != "ACME" {
+		t.Errorf("Unexpected JSONPath result: %v", data.ExtractedData["store"])
+	}
+	if prices, ok := data.ExtractedData["prices"].([]interface{}); !ok || len(prices) != 2 || prices[1] != float64(12) {
+		t.Errorf("JSON values should keep their type, got %v", data.ExtractedData["prices"])
+	}
+	products, _ := data.ExtractedData["products"].([]interface{})
+	if len(products) != 2 || products[1].(map[string]interface{})["name"] != "GADGET" {
+		t.Errorf("Unexpected nested JSONPath results: %v", data.ExtractedData["products"])
+	}
+
+	// RSS feed
+	data = scrape("/rss")
+	feed, ok := data.Content.(*Feed)
+	if !ok || feed.Format != FeedFormatRSS || len(feed.Items) != 2 {
+		t.Fatalf("Should parse the RSS feed, got %#v", data.Content)
+	}
+	first := feed.Items[0]
+	if first.Title != "First post" || first.ID != "post-1" || first.Published == nil || len(first.Authors) != 1 || first.Categories[0] != "go" {
+		t.Errorf("Unexpected RSS item: %+v", first)
+	}
+	if data.Title != "Example News" || len(data.Links) != 2 {
+		t.Errorf("Feed title and item links should be copied, got %q %v", data.Title, data.Links)
+	}
+
+	// Atom feed served as text/xml
+	data = scrape("/atom")
+	feed, ok = data.Content.(*Feed)
+	if !ok || feed.Format != FeedFormatAtom || len(feed.Items) != 1 {
+		t.Fatalf("Should parse the Atom feed, got %#v", data.Content)
+	}
+	if entry := feed.Items[0]; entry.Link != "https://example.com/atom-entry" || entry.Authors[0] != "John" || entry.Categories[0] != "news" {
+		t.Errorf("Unexpected Atom entry: %+v", entry)
+	}
+
+	// Plain XML with XPath extraction
+	data = scrape("/catalog.xml",
+		ExtractionField{Name: "titles", Selector: "xpath://book/title", Multiple: true},
+		ExtractionField{Name: "ids", Selector: "xpath://book/@id", Multiple: true},
+	)
+	if xml, ok := data.Content.(*XMLContent); !ok || xml.Root != "catalog" {
+		t.Errorf("Unexpected XML content: %#v", data.Content)
+	}
+	if ids, _ := data.ExtractedData["ids"].([]interface{}); len(ids) != 2 || ids[1] != "b2" {
+		t.Errorf("Unexpected XPath attribute results: %v", data.ExtractedData["ids"])
+	}
+	if titles, _ := data.ExtractedData["titles"].([]interface{}); len(titles) != 2 || titles[0] != "Go" {
+		t.Errorf("Unexpected XPath results: %v", data.ExtractedData["titles"])
+	}
+
+	// Plain text with regex extraction
+	data = scrape("/notes.txt", ExtractionField{Name: "orders", Selector: `regex:Order: (\d+)`, Multiple: true, Type: TypeInt})
+	if orders, _ := data.ExtractedData["orders"].([]interface{}); len(orders) != 2 || orders[1] != int64(5678) {
+		t.Errorf("Unexpected regex results on text: %v", data.ExtractedData["orders"])
+	}
+
+	// PDF text extraction
+	data = scrape("/doc.pdf")
+	if pdf, ok := data.Content.(*PDFContent); !ok || pdf.Pages != 1 {
+		t.Errorf("Unexpected PDF content: %#v", data.Content)
+	}
+	if !strings.Contains(data.Text, "Invoice 42") {
+		t.Errorf("Should extract the PDF text, got %q", data.Text)
+	}
+
+	// Unsupported content
+	data = scrape("/image.png")
+	if data.Status != ScrapeStatusUnsupportedContentType || data.ContentType != "image/png" {
+		t.Errorf("Images should be reported as unsupported, got %s %s", data.Status, data.ContentType)
+	}
+}
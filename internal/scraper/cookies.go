@@ -0,0 +1,328 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// namePattern restricts session and secret names to safe file names.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Cookie is a cookie seeded into a request or set by a response.
+type Cookie struct {
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Domain   string     `json:"domain,omitempty"`
+	Path     string     `json:"path,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	MaxAge   int        `json:"max_age,omitempty"`
+	Secure   bool       `json:"secure,omitempty"`
+	HTTPOnly bool       `json:"http_only,omitempty"`
+	SameSite string     `json:"same_site,omitempty"`
+}
+
+func newCookie(c *http.Cookie) Cookie {
+	cookie := Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		MaxAge:   c.MaxAge,
+		Secure:   c.Secure,
+		HTTPOnly: c.HttpOnly,
+	}
+	if !c.Expires.IsZero() {
+		expires := c.Expires
+		cookie.Expires = &expires
+	}
+	switch c.SameSite {
+	case http.SameSiteLaxMode:
+		cookie.SameSite = "lax"
+	case http.SameSiteStrictMode:
+		cookie.SameSite = "strict"
+	case http.SameSiteNoneMode:
+		cookie.SameSite = "none"
+	}
+	return cookie
+}
+
+func (c Cookie) httpCookie() *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		MaxAge:   c.MaxAge,
+		Secure:   c.Secure,
+		HttpOnly: c.HTTPOnly,
+	}
+	if c.Expires != nil {
+		cookie.Expires = *c.Expires
+	}
+	switch c.SameSite {
+	case "lax":
+		cookie.SameSite = http.SameSiteLaxMode
+	case "strict":
+		cookie.SameSite = http.SameSiteStrictMode
+	case "none":
+		cookie.SameSite = http.SameSiteNoneMode
+	}
+	return cookie
+}
+
+// storedCookie is a cookie together with the URL that set it, which the jar
+// needs to derive the default domain and path when it is replayed.
+type storedCookie struct {
+	URL    string `json:"url"`
+	Cookie Cookie `json:"cookie"`
+}
+
+// cookieJar is a cookie jar that also keeps the cookies it was given, since
+// net/http/cookiejar cannot list its contents for persistence.
+type cookieJar struct {
+	jar *cookiejar.Jar
+
+	mu      sync.Mutex
+	cookies map[string]storedCookie
+}
+
+func newCookieJar() *cookieJar {
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	return &cookieJar{jar: jar, cookies: make(map[string]storedCookie)}
+}
+
+func (j *cookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	origin := url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}
+	for _, c := range cookies {
+		key := u.Hostname() + "|" + c.Domain + "|" + c.Path + "|" + c.Name
+		if c.MaxAge < 0 || (!c.Expires.IsZero() && c.Expires.Before(now)) {
+			delete(j.cookies, key)
+			continue
+		}
+		// Relative lifetimes are stored as absolute ones
+		cookie := newCookie(c)
+		if c.MaxAge > 0 {
+			expires := now.Add(time.Duration(c.MaxAge) * time.Second)
+			cookie.Expires = &expires
+			cookie.MaxAge = 0
+		}
+		j.cookies[key] = storedCookie{URL: origin.String(), Cookie: cookie}
+	}
+}
+
+func (j *cookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// seed adds cookies for target. Cookies without a domain are host-only
+// cookies of the target.
+func (j *cookieJar) seed(target *url.URL, cookies []Cookie) {
+	for _, c := range cookies {
+		u := *target
+		if c.Domain != "" {
+			u.Host = c.Domain
+			if c.Domain[0] == '.' {
+				u.Host = c.Domain[1:]
+			}
+		}
+		j.SetCookies(&u, []*http.Cookie{c.httpCookie()})
+	}
+}
+
+func (j *cookieJar) snapshot() []storedCookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	cookies := make([]storedCookie, 0, len(j.cookies))
+	for _, c := range j.cookies {
+		cookies = append(cookies, c)
+	}
+	return cookies
+}
+
+func (j *cookieJar) restore(cookies []storedCookie) {
+	for _, c := range cookies {
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			continue
+		}
+		j.SetCookies(u, []*http.Cookie{c.Cookie.httpCookie()})
+	}
+}
+
+// cookieRecorder wraps the jar of a single request and records the cookies
+// set by its responses, including those of redirects.
+type cookieRecorder struct {
+	http.CookieJar
+	set []Cookie
+}
+
+func (r *cookieRecorder) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	for _, c := range cookies {
+		r.set = append(r.set, newCookie(c))
+	}
+	r.CookieJar.SetCookies(u, cookies)
+}
+
+type cookieJarKey struct{}
+
+func cookieJarFrom(ctx context.Context) *cookieJar {
+	jar, _ := ctx.Value(cookieJarKey{}).(*cookieJar)
+	return jar
+}
+
+// sessionStore keeps named cookie sessions in memory and, when a directory is
+// configured, on disk so that they survive restarts.
+type sessionStore struct {
+	mu       sync.Mutex
+	dir      string
+	sessions map[string]*cookieJar
+	// Serializes saves so that an older snapshot never replaces a newer one
+	saveMu sync.Mutex
+}
+
+func newSessionStore(dir string) *sessionStore {
+	return &sessionStore{dir: dir, sessions: make(map[string]*cookieJar)}
+}
+
+// open returns the jar of the named session, loading it from disk on first
+// use.
+func (st *sessionStore) open(name string) (*cookieJar, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if jar, ok := st.sessions[name]; ok {
+		return jar, nil
+	}
+
+	jar := newCookieJar()
+	if st.dir != "" {
+		data, err := os.ReadFile(st.path(name))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read session %s: %w", name, err)
+		}
+		if err == nil {
+			var cookies []storedCookie
+			if err := json.Unmarshal(data, &cookies); err != nil {
+				return nil, fmt.Errorf("failed to parse session %s: %w", name, err)
+			}
+			jar.restore(cookies)
+		}
+	}
+	st.sessions[name] = jar
+	return jar, nil
+}
+
+// save writes the named session to disk.
+func (st *sessionStore) save(name string, jar *cookieJar) error {
+	if st.dir == "" {
+		return nil
+	}
+
+	st.saveMu.Lock()
+	defer st.saveMu.Unlock()
+
+	data, err := json.MarshalIndent(jar.snapshot(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(st.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	// Write to a temporary file of its own first so a crash or another
+	// writer cannot leave a partial session
+	tmp, err := os.CreateTemp(st.dir, name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write session %s: %w", name, err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write session %s: %w", name, err)
+	}
+	if err := os.Rename(tmp.Name(), st.path(name)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// remove forgets the named session.
+func (st *sessionStore) remove(name string) error {
+	st.mu.Lock()
+	delete(st.sessions, name)
+	st.mu.Unlock()
+
+	if st.dir == "" {
+		return nil
+	}
+	if err := os.Remove(st.path(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (st *sessionStore) path(name string) string {
+	return filepath.Join(st.dir, name+".json")
+}
+
+// withCookieJar attaches the cookie jar for a scrape or crawl of target to
+// ctx: the jar of the named session in the options, or a new jar. Nested
+// calls keep the jar already attached. The returned function persists the
+// session and must be called once the requests are done.
+func (s *Service) withCookieJar(ctx context.Context, target string, options *CrawlingOptions) (context.Context, func(), error) {
+	if cookieJarFrom(ctx) != nil {
+		return ctx, func() {}, nil
+	}
+
+	jar := newCookieJar()
+	done := func() {}
+	if options.Session != "" {
+		var err error
+		if jar, err = s.sessions.open(options.Session); err != nil {
+			return ctx, nil, err
+		}
+		done = func() {
+			if err := s.sessions.save(options.Session, jar); err != nil {
+				s.logger.Warnf("Failed to persist session %s: %v", options.Session, err)
+			}
+		}
+	}
+
+	if len(options.Cookies) > 0 {
+		if u, err := url.Parse(target); err == nil {
+			jar.seed(u, options.Cookies)
+		}
+	}
+
+	return context.WithValue(ctx, cookieJarKey{}, jar), done, nil
+}
+
+// ClearSession removes a named cookie session.
+func (s *Service) ClearSession(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid session name %q", name)
+	}
+	return s.sessions.remove(name)
+}
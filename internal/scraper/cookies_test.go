@@ -0,0 +1,160 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+
+	"web-scraper-api/internal/config"
+	"web-scraper-api/internal/logger"
+)
+
+func newCookieTestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/consent":
+			// The consent cookie is set on a redirect
+			http.SetCookie(w, &http.Cookie{Name: "consent", Value: "yes", Path: "/"})
+			http.Redirect(w, r, "/page", http.StatusFound)
+			return
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "abc123", Path: "/", HttpOnly: true, MaxAge: 3600})
+		}
+
+		title := "anonymous"
+		if c, err := r.Cookie("sid"); err == nil {
+			title = "sid=" + c.Value
+		} else if c, err := r.Cookie("consent"); err == nil {
+			title = "consent=" + c.Value
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><head><title>%s</title></head><body><a href="/page">page</a></body></html>`, title)
+	}))
+}
+
+func TestScrape_CookiesAcrossRedirects(t *testing.T) {
+	server := newCookieTestServer()
+	defer server.Close()
+
+	service := newTestService()
+	data, err := service.ScrapeWebsiteWithOptions(context.Background(), server.URL+"/consent", &CrawlingOptions{})
+	if err != nil {
+		t.Fatalf("Should not return an error: %v", err)
+	}
+	if data.Title != "consent=yes" {
+		t.Errorf("Cookies set on a redirect should be sent to its target, got %q", data.Title)
+	}
+	if len(data.SetCookies) != 1 || data.SetCookies[0].Name != "consent" || data.SetCookies[0].Value != "yes" {
+		t.Errorf("Should expose the Set-Cookie values, got %+v", data.SetCookies)
+	}
+
+	// Separate scrapes don't share cookies
+	data, _ = service.ScrapeWebsiteWithOptions(context.Background(), server.URL+"/page", &CrawlingOptions{})
+	if data.Title != "anonymous" {
+		t.Errorf("Scrapes without a session should start without cookies, got %q", data.Title)
+	}
+
+	// Seeded cookies
+	data, _ = service.ScrapeWebsiteWithOptions(context.Background(), server.URL+"/page", &CrawlingOptions{
+		Cookies: []Cookie{{Name: "sid", Value: "seeded"}},
+	})
+	if data.Title != "sid=seeded" {
+		t.Errorf("Should send seeded cookies, got %q", data.Title)
+	}
+}
+
+func TestCrawl_SharesCookieJar(t *testing.T) {
+	server := newCookieTestServer()
+	defer server.Close()
+
+	service := newTestService()
+	result, err := service.Crawl(context.Background(), server.URL+"/login", &CrawlingOptions{MaxDepth: 1, MaxPages: 2})
+	if err != nil {
+		t.Fatalf("Should not return an error: %v", err)
+	}
+	if len(result.Pages) != 2 || result.Pages[1].Data == nil {
+		t.Fatalf("Should crawl the login page and its link, got %d pages", len(result.Pages))
+	}
+	if title := result.Pages[1].Data.Title; title != "sid=abc123" {
+		t.Errorf("Crawled pages should share the session cookie, got %q", title)
+	}
+}
+
+func TestScrape_NamedSessionPersists(t *testing.T) {
+	server := newCookieTestServer()
+	defer server.Close()
+
+	dir := t.TempDir()
+	newService := func() *Service {
+		return NewServiceWithConfig(&config.Config{
+			Scraping: config.ScrapingConfig{AllowPrivateNetworks: true, SessionDir: dir},
+		}, logger.New("error"))
+	}
+
+	options := &CrawlingOptions{Session: "shop"}
+	if _, err := newService().ScrapeWebsiteWithOptions(context.Background(), server.URL+"/login", options); err != nil {
+		t.Fatalf("Should not return an error: %v", err)
+	}
+
+	// A new service, as after a restart, loads the session from disk
+	service := newService()
+	data, err := service.ScrapeWebsiteWithOptions(context.Background(), server.URL+"/page", options)
+	if err != nil {
+		t.Fatalf("Should not return an error: %v", err)
+	}
+	if data.Title != "sid=abc123" {
+		t.Errorf("Should reuse the session cookies, got %q", data.Title)
+	}
+
+	if err := service.ClearSession("shop"); err != nil {
+		t.Fatalf("Should clear the session: %v", err)
+	}
+	data, _ = service.ScrapeWebsiteWithOptions(context.Background(), server.URL+"/page", options)
+	if data.Title != "anonymous" {
+		t.Errorf("Cleared sessions should start empty, got %q", data.Title)
+	}
+
+	if err := (&CrawlingOptions{Session: "../etc"}).Validate(); err == nil {
+		t.Error("Should reject session names that are not plain names")
+	}
+}
+
+func TestSessionStore_ConcurrentSaves(t *testing.T) {
+	dir := t.TempDir()
+	store := newSessionStore(dir)
+	jar, err := store.open("shop")
+	if err != nil {
+		t.Fatalf("Should open the session: %v", err)
+	}
+	u, _ := url.Parse("https://example.com/")
+	jar.SetCookies(u, []*http.Cookie{{Name: "sid", Value: "abc123", Path: "/", MaxAge: 3600}})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.save("shop", jar); err != nil {
+				t.Errorf("Should save the session: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Should leave only the session file, got %d files", len(entries))
+	}
+	restored, err := newSessionStore(dir).open("shop")
+	if err != nil {
+		t.Fatalf("Should load the saved session: %v", err)
+	}
+	if cookies := restored.Cookies(u); len(cookies) != 1 || cookies[0].Value != "abc123" {
+		t.Errorf("Should restore the session cookies, got %v", cookies)
+	}
+}
@@ -0,0 +1,225 @@
+package scraper
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// defaultCrawlMaxPages caps a crawl when the options don't set MaxPages.
+const defaultCrawlMaxPages = 100
+
+type CrawledPage struct {
+	URL       string       `json:"url"`
+	Depth     int          `json:"depth"`
+	ParentURL string       `json:"parent_url,omitempty"`
+	Data      *ScrapedData `json:"data,omitempty"`
+	Error     string       `json:"error,omitempty"`
+}
+
+type CrawlResult struct {
+	SeedURL      string         `json:"seed_url"`
+	SeedMode     SeedMode       `json:"seed_mode"`
+	Sitemaps     []string       `json:"sitemaps,omitempty"`
+	Pages        []*CrawledPage `json:"pages"`
+	PageCount    int            `json:"page_count"`
+	ErrorCount   int            `json:"error_count"`
+	BlockedCount int            `json:"blocked_count"`
+	MaxDepth     int            `json:"max_depth"`
+	StartedAt    time.Time      `json:"started_at"`
+	EndedAt      time.Time      `json:"ended_at"`
+	Duration     time.Duration  `json:"duration"`
+}
+
+// crawlTarget is an entry of the crawl frontier.
+type crawlTarget struct {
+	url    string
+	depth  int
+	parent string
+}
+
+// Crawl fetches seedURL and follows the links it discovers breadth-first.
+// The seed has depth 0; links found on a page at depth d are fetched at depth
+// d+1 as long as that does not exceed options.MaxDepth. The crawl stops once
+// options.MaxPages pages have been fetched. Discovered links are filtered with
+// the include/exclude patterns and allowed domains; without allowed domains
+// the crawl stays on the seed's host. With SeedModeSitemap every URL listed in
+// the site's sitemaps is a seed at depth 0 instead.
+func (s *Service) Crawl(ctx context.Context, seedURL string, options *CrawlingOptions) (*CrawlResult, error) {
+	if err := s.checkURL(seedURL); err != nil {
+		return nil, err
+	}
+	seed, _ := url.Parse(seedURL)
+
+	// All pages of the crawl share one cookie jar
+	ctx, saveSession, err := s.withCookieJar(ctx, seedURL, options)
+	if err != nil {
+		return nil, err
+	}
+	defer saveSession()
+
+	if ctx, err = s.withAuth(ctx, seedURL, options); err != nil {
+		return nil, err
+	}
+
+	maxPages := options.MaxPages
+	if maxPages <= 0 {
+		maxPages = defaultCrawlMaxPages
+	}
+	maxDepth := options.MaxDepth
+	if maxDepth < 0 {
+		maxDepth = 0
+	}
+
+	s.logger.Infof("Crawling %s (max depth: %d, max pages: %d)", seedURL, maxDepth, maxPages)
+
+	result := &CrawlResult{
+		SeedURL:   seedURL,
+		SeedMode:  SeedModeURL,
+		Pages:     make([]*CrawledPage, 0),
+		MaxDepth:  maxDepth,
+		StartedAt: time.Now(),
+	}
+
+	visited := make(map[string]bool)
+	var level []crawlTarget
+
+	if options.SeedMode == SeedModeSitemap {
+		sitemap, err := s.Sitemap(ctx, seedURL, options)
+		if err != nil {
+			return nil, err
+		}
+		result.SeedMode = SeedModeSitemap
+		result.Sitemaps = sitemap.Sitemaps
+
+		for _, entry := range sitemap.URLs {
+			u, err := url.Parse(entry.Loc)
+			if err != nil {
+				continue
+			}
+			key := NormalizeURL(u, options)
+			if visited[key] {
+				continue
+			}
+			visited[key] = true
+			level = append(level, crawlTarget{url: key, depth: 0, parent: entry.Sitemap})
+		}
+	} else {
+		seedKey := NormalizeURL(seed, options)
+		visited[seedKey] = true
+		level = append(level, crawlTarget{url: seedKey, depth: 0})
+	}
+
+	for len(level) > 0 && len(result.Pages) < maxPages {
+		if ctx.Err() != nil {
+			break
+		}
+
+		// Never fetch more than the remaining page budget
+		if remaining := maxPages - len(result.Pages); len(level) > remaining {
+			level = level[:remaining]
+		}
+
+		pages := s.crawlLevel(ctx, level, options)
+
+		var next []crawlTarget
+		for i, page := range pages {
+			crawled := &CrawledPage{
+				URL:       level[i].url,
+				Depth:     level[i].depth,
+				ParentURL: level[i].parent,
+			}
+			if page.err != nil {
+				crawled.Error = page.err.Error()
+				result.ErrorCount++
+			} else {
+				crawled.Data = page.page.data
+				if crawled.Data.Status == ScrapeStatusBlockedByRobots {
+					result.BlockedCount++
+				}
+				// Don't crawl a redirect target again under its final URL
+				if page.page.pageURL != nil {
+					visited[NormalizeURL(page.page.pageURL, options)] = true
+				}
+			}
+			result.Pages = append(result.Pages, crawled)
+
+			if page.err != nil || page.page.doc == nil || level[i].depth >= maxDepth {
+				continue
+			}
+
+			for _, link := range s.discoverLinks(page.page, seed, options) {
+				if visited[link] {
+					continue
+				}
+				visited[link] = true
+				next = append(next, crawlTarget{url: link, depth: level[i].depth + 1, parent: level[i].url})
+			}
+		}
+
+		level = next
+	}
+
+	result.PageCount = len(result.Pages)
+	result.EndedAt = time.Now()
+	result.Duration = result.EndedAt.Sub(result.StartedAt)
+
+	s.logger.Infof("Crawl completed: %s - %d pages, %d errors in %v", seedURL, result.PageCount, result.ErrorCount, result.Duration)
+
+	return result, nil
+}
+
+type crawlOutcome struct {
+	page *scrapedPage
+	err  error
+}
+
+// crawlLevel fetches all targets of one depth level concurrently and returns
+// the outcomes in the same order as the targets.
+func (s *Service) crawlLevel(ctx context.Context, targets []crawlTarget, options *CrawlingOptions) []crawlOutcome {
+	outcomes := make([]crawlOutcome, len(targets))
+
+	// Concurrency and per-host rate limits are enforced by the fetch scheduler
+	var wg sync.WaitGroup
+
+	for i, target := range targets {
+		wg.Add(1)
+
+		go func(i int, u string) {
+			defer wg.Done()
+
+			pageCtx := ctx
+			if options.Timeout > 0 {
+				var cancel context.CancelFunc
+				pageCtx, cancel = context.WithTimeout(ctx, options.Timeout)
+				defer cancel()
+			}
+
+			page, err := s.scrapePage(pageCtx, u, options)
+			if err != nil {
+				s.logger.Errorf("Error crawling %s: %v", u, err)
+			}
+			outcomes[i] = crawlOutcome{page: page, err: err}
+		}(i, target.url)
+	}
+
+	wg.Wait()
+
+	return outcomes
+}
+
+// discoverLinks returns the normalized links of a page that pass the crawl
+// filters.
+func (s *Service) discoverLinks(page *scrapedPage, seed *url.URL, options *CrawlingOptions) []string {
+	var links []string
+	for _, u := range extractURLs(page.doc, "a[href]", "href", page.baseURL, options) {
+		// Stay on the seed host unless allowed domains were given explicitly
+		if len(options.AllowedDomains) == 0 && !isInternalURL(u, seed) {
+			continue
+		}
+		links = append(links, u.String())
+	}
+
+	return s.filterUrls(links, options)
+}
@@ -0,0 +1,108 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newCrawlTestServer() *httptest.Server {
+	pages := map[string]string{
+		"/":  `<a href="/a">A</a> <a href="b#section">B</a> <a href="https://external.example/">Ext</a> <a href="mailto:x@y.z">Mail</a>`,
+		"/a": `<a href="/c">C</a> <a href="/">Home</a>`,
+		"/b": `<a href="/a">A</a>`,
+		"/c": `<a href="/d">D</a>`,
+		"/d": `<p>leaf</p>`,
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "<html><head><title>%s</title></head><body>%s</body></html>", r.URL.Path, body)
+	}))
+}
+
+func TestCrawl_RespectsMaxDepth(t *testing.T) {
+	server := newCrawlTestServer()
+	defer server.Close()
+
+	service := newTestService()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := service.Crawl(ctx, server.URL, &CrawlingOptions{MaxDepth: 1, MaxPages: 10})
+	if err != nil {
+		t.Fatalf("Crawl should not return an error: %v", err)
+	}
+
+	if result.PageCount != 3 {
+		t.Fatalf("Should crawl 3 pages, got %d", result.PageCount)
+	}
+
+	depths := make(map[string]int)
+	for _, page := range result.Pages {
+		depths[page.URL] = page.Depth
+	}
+
+	if depths[server.URL+"/"] != 0 {
+		t.Errorf("Seed should have depth 0, got %d", depths[server.URL+"/"])
+	}
+	if depth, ok := depths[server.URL+"/a"]; !ok || depth != 1 {
+		t.Errorf("/a should be crawled at depth 1")
+	}
+	if depth, ok := depths[server.URL+"/b"]; !ok || depth != 1 {
+		t.Errorf("/b should be crawled at depth 1")
+	}
+	if _, ok := depths[server.URL+"/c"]; ok {
+		t.Errorf("/c is beyond max depth and should not be crawled")
+	}
+
+	for _, page := range result.Pages {
+		if page.Depth == 1 && page.ParentURL != server.URL+"/" {
+			t.Errorf("Parent of %s should be the seed, got '%s'", page.URL, page.ParentURL)
+		}
+	}
+}
+
+func TestCrawl_RespectsMaxPages(t *testing.T) {
+	server := newCrawlTestServer()
+	defer server.Close()
+
+	service := newTestService()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := service.Crawl(ctx, server.URL, &CrawlingOptions{MaxDepth: 10, MaxPages: 4})
+	if err != nil {
+		t.Fatalf("Crawl should not return an error: %v", err)
+	}
+
+	if result.PageCount != 4 {
+		t.Fatalf("Should stop after 4 pages, got %d", result.PageCount)
+	}
+
+	seen := make(map[string]bool)
+	for _, page := range result.Pages {
+		if seen[page.URL] {
+			t.Errorf("Page %s was crawled twice", page.URL)
+		}
+		seen[page.URL] = true
+	}
+}
+
+func TestCrawl_InvalidSeed(t *testing.T) {
+	service := newTestService()
+
+	_, err := service.Crawl(context.Background(), "ftp://example.com", &CrawlingOptions{})
+	if err == nil {
+		t.Fatal("Should return an error for a non-http seed URL")
+	}
+}
@@ -0,0 +1,74 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+type ErrorKind string
+
+const (
+	ErrorKindNetwork    ErrorKind = "network"
+	ErrorKindTimeout    ErrorKind = "timeout"
+	ErrorKindHTTPStatus ErrorKind = "http_status"
+	ErrorKindParse      ErrorKind = "parse"
+	ErrorKindPolicy     ErrorKind = "policy"
+	// Login failed or credentials could not be obtained
+	ErrorKindAuth ErrorKind = "auth"
+)
+
+// ScrapeError is returned by the scraping methods so that callers can tell
+// network, HTTP status, parse and policy failures apart.
+type ScrapeError struct {
+	Kind       ErrorKind
+	URL        string
+	StatusCode int
+	Attempts   int
+	Err        error
+}
+
+func (e *ScrapeError) Error() string {
+	msg := fmt.Sprintf("%s error for %s", e.Kind, e.URL)
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(" (status %d)", e.StatusCode)
+	}
+	if e.Attempts > 1 {
+		msg += fmt.Sprintf(" after %d attempts", e.Attempts)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *ScrapeError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorKindOf returns the kind of a scraping error, or an empty kind if err
+// is not a *ScrapeError.
+func ErrorKindOf(err error) ErrorKind {
+	var scrapeErr *ScrapeError
+	if errors.As(err, &scrapeErr) {
+		return scrapeErr.Kind
+	}
+	return ""
+}
+
+// classifyRequestError maps an error returned by http.Client.Do to its kind.
+func classifyRequestError(err error) ErrorKind {
+	var blocked *BlockedURLError
+	if errors.Is(err, ErrTooManyRedirects) || errors.As(err, &blocked) {
+		return ErrorKindPolicy
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorKindTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorKindTimeout
+	}
+	return ErrorKindNetwork
+}
@@ -0,0 +1,339 @@
+package scraper
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/antchfx/xmlquery"
+)
+
+const (
+	SourceText = "text"
+	SourceHTML = "html"
+	SourceAttr = "attr"
+
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeFloat  = "float"
+	TypeBool   = "bool"
+	TypeDate   = "date"
+
+	TransformTrim    = "trim"
+	TransformRegex   = "regex"
+	TransformReplace = "replace"
+	TransformLower   = "lower"
+	TransformUpper   = "upper"
+)
+
+// ExtractionField describes one value to extract from a page. A field with
+// nested Fields yields objects: Selector picks the containers and the nested
+// fields are evaluated relative to each container.
+type ExtractionField struct {
+	Name string `json:"name"`
+	// Selector is a CSS selector, optionally prefixed with "css:", or an
+	// "xpath:", "regex:" or "jsonpath:" expression
+	Selector string `json:"selector"`
+	// Source is "text" (default), "html" for the inner HTML or "attr"
+	Source    string `json:"source,omitempty"`
+	Attribute string `json:"attribute,omitempty"`
+	// Multiple returns all matches as a list instead of the first one
+	Multiple   bool              `json:"multiple,omitempty"`
+	Transforms []Transform       `json:"transforms,omitempty"`
+	Type       string            `json:"type,omitempty"`
+	DateFormat string            `json:"date_format,omitempty"`
+	Fields     []ExtractionField `json:"fields,omitempty"`
+}
+
+// Transform is a single post-processing step applied to an extracted value.
+type Transform struct {
+	// Op is one of trim, regex, replace, lower or upper
+	Op string `json:"op"`
+	// Pattern is the regular expression for regex and replace
+	Pattern string `json:"pattern,omitempty"`
+	// Group selects the capture group for regex (default: 1 if present, else 0)
+	Group       *int   `json:"group,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// ValidateExtractionRules checks the rules for missing names or selectors,
+// unknown sources, types and transforms, and invalid regular expressions.
+func ValidateExtractionRules(rules []ExtractionField) error {
+	names := make(map[string]bool)
+	for i := range rules {
+		field := &rules[i]
+		if field.Name == "" {
+			return fmt.Errorf("extraction rule %d: name is required", i)
+		}
+		if names[field.Name] {
+			return fmt.Errorf("extraction rule %q: duplicate name", field.Name)
+		}
+		names[field.Name] = true
+
+		if err := field.validate(); err != nil {
+			return fmt.Errorf("extraction rule %q: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+func (f *ExtractionField) validate() error {
+	if f.Selector == "" {
+		return fmt.Errorf("selector is required")
+	}
+	sel, err := compileSelector(f.Selector)
+	if err != nil {
+		return err
+	}
+	if sel.kind == SelectorRegex {
+		if f.Source != "" && f.Source != SourceText {
+			return fmt.Errorf("source %q is not supported for regex selectors", f.Source)
+		}
+		if len(f.Fields) > 0 {
+			return fmt.Errorf("nested fields are not supported for regex selectors")
+		}
+	}
+
+	switch f.Source {
+	case "", SourceText, SourceHTML:
+	case SourceAttr:
+		if f.Attribute == "" {
+			return fmt.Errorf("attribute is required for source %q", SourceAttr)
+		}
+	default:
+		return fmt.Errorf("unknown source %q", f.Source)
+	}
+
+	switch f.Type {
+	case "", TypeString, TypeInt, TypeFloat, TypeBool, TypeDate:
+	default:
+		return fmt.Errorf("unknown type %q", f.Type)
+	}
+
+	for _, t := range f.Transforms {
+		switch t.Op {
+		case TransformTrim, TransformLower, TransformUpper:
+		case TransformRegex, TransformReplace:
+			re, err := regexp.Compile(t.Pattern)
+			if err != nil {
+				return fmt.Errorf("invalid %s pattern: %w", t.Op, err)
+			}
+			if t.Group != nil && (*t.Group < 0 || *t.Group > re.NumSubexp()) {
+				return fmt.Errorf("regex group %d does not exist", *t.Group)
+			}
+		default:
+			return fmt.Errorf("unknown transform %q", t.Op)
+		}
+	}
+
+	if len(f.Fields) > 0 {
+		return ValidateExtractionRules(f.Fields)
+	}
+	return nil
+}
+
+// extractor evaluates extraction rules against a document and caches the
+// compiled selectors and regular expressions.
+type extractor struct {
+	selectors map[string]*selector
+	patterns  map[string]*regexp.Regexp
+	errors    []string
+}
+
+func newExtractor() *extractor {
+	return &extractor{
+		selectors: make(map[string]*selector),
+		patterns:  make(map[string]*regexp.Regexp),
+	}
+}
+
+func (e *extractor) selector(raw string) (*selector, error) {
+	if sel, ok := e.selectors[raw]; ok {
+		return sel, nil
+	}
+	sel, err := compileSelector(raw)
+	if err != nil {
+		return nil, err
+	}
+	e.selectors[raw] = sel
+	return sel, nil
+}
+
+func (e *extractor) pattern(expr string) (*regexp.Regexp, error) {
+	if re, ok := e.patterns[expr]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	e.patterns[expr] = re
+	return re, nil
+}
+
+// extract evaluates rules relative to root, which is an HTML selection, an
+// XML node or a JSON or text value, and returns one entry per rule.
+func (e *extractor) extract(root interface{}, rules []ExtractionField) map[string]interface{} {
+	result := make(map[string]interface{}, len(rules))
+	for i := range rules {
+		result[rules[i].Name] = e.extractField(root, &rules[i])
+	}
+	return result
+}
+
+func (e *extractor) extractField(root interface{}, field *ExtractionField) interface{} {
+	var matches []selectorMatch
+	sel, err := e.selector(field.Selector)
+	if err == nil {
+		matches, err = sel.matches(root)
+	}
+	if err != nil {
+		e.errors = append(e.errors, fmt.Sprintf("%s: %v", field.Name, err))
+	}
+
+	var values []interface{}
+	for _, match := range matches {
+		if len(field.Fields) > 0 && match.node != nil {
+			values = append(values, e.extract(match.node, field.Fields))
+		} else {
+			values = append(values, e.fieldValue(match, field))
+		}
+		if !field.Multiple {
+			break
+		}
+	}
+
+	if field.Multiple {
+		if values == nil {
+			return []interface{}{}
+		}
+		return values
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+// fieldValue reads, transforms and converts the value of a single match.
+func (e *extractor) fieldValue(match selectorMatch, field *ExtractionField) interface{} {
+	raw := match.value
+	switch node := match.node.(type) {
+	case *goquery.Selection:
+		switch field.Source {
+		case SourceHTML:
+			raw, _ = node.Html()
+		case SourceAttr:
+			raw, _ = node.Attr(field.Attribute)
+		default:
+			raw = node.Text()
+		}
+	case *xmlquery.Node:
+		switch field.Source {
+		case SourceHTML:
+			raw = node.OutputXML(false)
+		case SourceAttr:
+			raw = node.SelectAttr(field.Attribute)
+		default:
+			raw = node.InnerText()
+		}
+	case jsonValue:
+		// JSON values keep their type unless they are transformed or converted
+		if len(field.Transforms) == 0 && field.Type == "" {
+			return node.value
+		}
+		raw = jsonText(node.value)
+	}
+
+	value, ok := e.applyTransforms(raw, field)
+	if !ok {
+		return nil
+	}
+	return e.convert(value, field)
+}
+
+func (e *extractor) applyTransforms(value string, field *ExtractionField) (string, bool) {
+	for _, t := range field.Transforms {
+		switch t.Op {
+		case TransformTrim:
+			value = strings.TrimSpace(value)
+		case TransformLower:
+			value = strings.ToLower(value)
+		case TransformUpper:
+			value = strings.ToUpper(value)
+		case TransformRegex, TransformReplace:
+			re, err := e.pattern(t.Pattern)
+			if err != nil {
+				e.errors = append(e.errors, fmt.Sprintf("%s: invalid pattern: %v", field.Name, err))
+				return "", false
+			}
+			if t.Op == TransformReplace {
+				value = re.ReplaceAllString(value, t.Replacement)
+				continue
+			}
+
+			match := re.FindStringSubmatch(value)
+			if match == nil {
+				return "", false
+			}
+			group := 0
+			if t.Group != nil {
+				group = *t.Group
+			} else if len(match) > 1 {
+				group = 1
+			}
+			if group >= len(match) {
+				return "", false
+			}
+			value = match[group]
+		}
+	}
+	return value, true
+}
+
+// convert turns a string value into the field's type. Conversion failures
+// are recorded and yield nil.
+func (e *extractor) convert(value string, field *ExtractionField) interface{} {
+	trimmed := strings.TrimSpace(value)
+
+	switch field.Type {
+	case TypeInt:
+		n, err := strconv.ParseInt(trimmed, 10, 64)
+		if err != nil {
+			e.errors = append(e.errors, fmt.Sprintf("%s: cannot convert %q to int", field.Name, trimmed))
+			return nil
+		}
+		return n
+	case TypeFloat:
+		f, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil {
+			e.errors = append(e.errors, fmt.Sprintf("%s: cannot convert %q to float", field.Name, trimmed))
+			return nil
+		}
+		return f
+	case TypeBool:
+		b, err := strconv.ParseBool(trimmed)
+		if err != nil {
+			e.errors = append(e.errors, fmt.Sprintf("%s: cannot convert %q to bool", field.Name, trimmed))
+			return nil
+		}
+		return b
+	case TypeDate:
+		layouts := []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02", time.RFC1123, time.RFC1123Z}
+		if field.DateFormat != "" {
+			layouts = []string{field.DateFormat}
+		}
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, trimmed); err == nil {
+				return t
+			}
+		}
+		e.errors = append(e.errors, fmt.Sprintf("%s: cannot convert %q to date", field.Name, trimmed))
+		return nil
+	default:
+		return value
+	}
+}
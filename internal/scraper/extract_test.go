@@ -0,0 +1,161 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const testProductsHTML = `<html><body>
+<h1 class="title">  Product list  </h1>
+<div class="product" data-sku="A-1">
+  <a class="name" href="/p/1">Widget</a>
+  <span class="price">Price: 1,299.50 EUR</span>
+  <span class="stock">true</span>
+  <time datetime="2026-03-01">March</time>
+</div>
+<div class="product" data-sku="B-2">
+  <a class="name" href="/p/2">Gadget</a>
+  <span class="price">Price: 15.00 EUR</span>
+  <span class="stock">false</span>
+  <time datetime="2026-04-15">April</time>
+</div>
+</body></html>`
+
+func TestExtractor_Rules(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(testProductsHTML))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+
+	rules := []ExtractionField{
+		{Name: "title", Selector: "h1.title", Transforms: []Transform{{Op: TransformTrim}, {Op: TransformUpper}}},
+		{Name: "names", Selector: ".product .name", Multiple: true},
+		{Name: "first_link", Selector: ".product a", Source: SourceAttr, Attribute: "href"},
+		{Name: "missing", Selector: ".does-not-exist"},
+		{
+			Name:     "products",
+			Selector: ".product",
+			Multiple: true,
+			Fields: []ExtractionField{
+				{Name: "sku", Selector: "a", Source: SourceAttr, Attribute: "href", Transforms: []Transform{{Op: TransformRegex, Pattern: `/p/(\d+)`}}, Type: TypeInt},
+				{Name: "price", Selector: ".price", Transforms: []Transform{{Op: TransformRegex, Pattern: `([\d,.]+)`}, {Op: TransformReplace, Pattern: `,`, Replacement: ""}}, Type: TypeFloat},
+				{Name: "in_stock", Selector: ".stock", Type: TypeBool},
+				{Name: "released", Selector: "time", Source: SourceAttr, Attribute: "datetime", Type: TypeDate},
+			},
+		},
+	}
+
+	if err := ValidateExtractionRules(rules); err != nil {
+		t.Fatalf("Rules should be valid: %v", err)
+	}
+
+	e := newExtractor()
+	result := e.extract(doc.Selection, rules)
+
+	if result["title"] != "PRODUCT LIST" {
+		t.Errorf("Unexpected title: %v", result["title"])
+	}
+	if names, ok := result["names"].([]interface{}); !ok || len(names) != 2 || names[1] != "Gadget" {
+		t.Errorf("Unexpected names: %v", result["names"])
+	}
+	if result["first_link"] != "/p/1" {
+		t.Errorf("Unexpected first link: %v", result["first_link"])
+	}
+	if result["missing"] != nil {
+		t.Errorf("Missing single value should be nil, got %v", result["missing"])
+	}
+
+	products, ok := result["products"].([]interface{})
+	if !ok || len(products) != 2 {
+		t.Fatalf("Should extract 2 products, got %v", result["products"])
+	}
+	first := products[0].(map[string]interface{})
+	if first["sku"] != int64(1) || first["price"] != 1299.5 || first["in_stock"] != true {
+		t.Errorf("Unexpected first product: %v", first)
+	}
+	if released, ok := first["released"].(time.Time); !ok || released.Format("2006-01-02") != "2026-03-01" {
+		t.Errorf("Unexpected release date: %v", first["released"])
+	}
+	if len(e.errors) != 0 {
+		t.Errorf("Should not report errors, got %v", e.errors)
+	}
+}
+
+func TestValidateExtractionRules_Invalid(t *testing.T) {
+	tests := []ExtractionField{
+		{Selector: "h1"},
+		{Name: "a"},
+		{Name: "a", Selector: "h1", Source: "attr"},
+		{Name: "a", Selector: "h1", Source: "json"},
+		{Name: "a", Selector: "h1", Type: "money"},
+		{Name: "a", Selector: "h1", Transforms: []Transform{{Op: TransformRegex, Pattern: "("}}},
+		{Name: "a", Selector: "h1", Transforms: []Transform{{Op: "reverse"}}},
+		{Name: "a", Selector: "div[["},
+		{Name: "a", Selector: "xpath://div[@class="},
+		{Name: "a", Selector: "regex:(unclosed"},
+		{Name: "a", Selector: "regex:x", Source: SourceAttr, Attribute: "href"},
+	}
+
+	for i, rule := range tests {
+		if err := ValidateExtractionRules([]ExtractionField{rule}); err == nil {
+			t.Errorf("Rule %d should be rejected: %+v", i, rule)
+		}
+	}
+}
+
+func TestExtractor_XPathAndRegexSelectors(t *testing.T) {
+	html := `<html><body>
+<dl><dt>Color</dt><dd>Red</dd><dt>Size</dt><dd>XL</dd></dl>
+<a href="/first">First</a><a href="/second">Second</a>
+<script>window.__STATE__ = {"price": 42, "sku": "X-9"};</script>
+</body></html>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+
+	rules := []ExtractionField{
+		{Name: "size", Selector: `xpath://dt[text()="Size"]/following-sibling::dd[1]`},
+		{Name: "hrefs", Selector: "xpath://a/@href", Multiple: true},
+		{Name: "link_count", Selector: "xpath:count(//a)", Type: TypeInt},
+		{Name: "price", Selector: `regex:"price":\s*(\d+)`, Type: TypeInt},
+		{Name: "css_title", Selector: "css:dl dt"},
+	}
+	if err := ValidateExtractionRules(rules); err != nil {
+		t.Fatalf("Rules should be valid: %v", err)
+	}
+
+	e := newExtractor()
+	result := e.extract(doc.Selection, rules)
+
+	if result["size"] != "XL" {
+		t.Errorf("Unexpected XPath sibling result: %v", result["size"])
+	}
+	if hrefs, ok := result["hrefs"].([]interface{}); !ok || len(hrefs) != 2 || hrefs[1] != "/second" {
+		t.Errorf("Unexpected XPath attribute results: %v", result["hrefs"])
+	}
+	if result["link_count"] != int64(2) {
+		t.Errorf("Unexpected XPath count: %v", result["link_count"])
+	}
+	if result["price"] != int64(42) {
+		t.Errorf("Unexpected regex result: %v", result["price"])
+	}
+	if result["css_title"] != "Color" {
+		t.Errorf("Unexpected CSS result: %v", result["css_title"])
+	}
+}
+
+func TestCrawlingOptions_ValidateCustomSelectors(t *testing.T) {
+	valid := &CrawlingOptions{CustomSelectors: map[string]string{"a": "h1", "b": "xpath://h1", "c": "regex:id=(\\d+)"}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Selectors should be valid: %v", err)
+	}
+
+	invalid := &CrawlingOptions{CustomSelectors: map[string]string{"bad": "xpath:///["}}
+	if err := invalid.Validate(); err == nil {
+		t.Error("Invalid XPath selector should be rejected")
+	}
+}
@@ -0,0 +1,226 @@
+package scraper
+
+import (
+	"strings"
+	"time"
+
+	"github.com/antchfx/xmlquery"
+)
+
+// Feed formats
+const (
+	FeedFormatRSS  = "rss"
+	FeedFormatAtom = "atom"
+	FeedFormatRDF  = "rdf"
+)
+
+// Feed is the normalized form of an RSS 2.0, RSS 1.0 (RDF) or Atom feed.
+type Feed struct {
+	Format      string     `json:"format"`
+	Title       string     `json:"title"`
+	Link        string     `json:"link,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Updated     *time.Time `json:"updated,omitempty"`
+	Items       []FeedItem `json:"items"`
+}
+
+// FeedItem is a single RSS item or Atom entry.
+type FeedItem struct {
+	ID         string     `json:"id,omitempty"`
+	Title      string     `json:"title"`
+	Link       string     `json:"link,omitempty"`
+	Summary    string     `json:"summary,omitempty"`
+	Content    string     `json:"content,omitempty"`
+	Authors    []string   `json:"authors,omitempty"`
+	Categories []string   `json:"categories,omitempty"`
+	Published  *time.Time `json:"published,omitempty"`
+	Updated    *time.Time `json:"updated,omitempty"`
+}
+
+// xmlRootElement returns the document element of an XML document.
+func xmlRootElement(doc *xmlquery.Node) *xmlquery.Node {
+	for n := doc.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type == xmlquery.ElementNode {
+			return n
+		}
+	}
+	return nil
+}
+
+// parseFeed normalizes a feed given its root element, or returns nil if the
+// document is not a feed.
+func parseFeed(root *xmlquery.Node) *Feed {
+	switch root.Data {
+	case "rss":
+		channel := xmlChild(root, "channel")
+		if channel == nil {
+			return nil
+		}
+		feed := parseRSSChannel(channel, xmlChildren(channel, "item"))
+		feed.Format = FeedFormatRSS
+		return feed
+	case "RDF":
+		channel := xmlChild(root, "channel")
+		if channel == nil {
+			return nil
+		}
+		// RSS 1.0 items are siblings of the channel
+		feed := parseRSSChannel(channel, xmlChildren(root, "item"))
+		feed.Format = FeedFormatRDF
+		return feed
+	case "feed":
+		return parseAtomFeed(root)
+	}
+	return nil
+}
+
+func parseRSSChannel(channel *xmlquery.Node, items []*xmlquery.Node) *Feed {
+	feed := &Feed{
+		Title:       xmlChildText(channel, "title"),
+		Link:        xmlChildText(channel, "link"),
+		Description: xmlChildText(channel, "description"),
+		Updated:     parseFeedDate(xmlChildText(channel, "lastBuildDate", "pubDate", "dc:date")),
+		Items:       make([]FeedItem, 0, len(items)),
+	}
+
+	for _, n := range items {
+		item := FeedItem{
+			ID:        xmlChildText(n, "guid"),
+			Title:     xmlChildText(n, "title"),
+			Link:      xmlChildText(n, "link"),
+			Summary:   xmlChildText(n, "description"),
+			Content:   xmlChildText(n, "content:encoded"),
+			Published: parseFeedDate(xmlChildText(n, "pubDate", "dc:date")),
+		}
+		if item.ID == "" {
+			item.ID = n.SelectAttr("rdf:about")
+		}
+		if item.Link == "" && strings.HasPrefix(item.ID, "http") {
+			item.Link = item.ID
+		}
+		for _, c := range xmlChildren(n, "author", "dc:creator") {
+			item.Authors = append(item.Authors, strings.TrimSpace(c.InnerText()))
+		}
+		for _, c := range xmlChildren(n, "category", "dc:subject") {
+			item.Categories = append(item.Categories, strings.TrimSpace(c.InnerText()))
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	return feed
+}
+
+func parseAtomFeed(root *xmlquery.Node) *Feed {
+	entries := xmlChildren(root, "entry")
+	feed := &Feed{
+		Format:      FeedFormatAtom,
+		Title:       xmlChildText(root, "title"),
+		Link:        atomLink(root),
+		Description: xmlChildText(root, "subtitle"),
+		Updated:     parseFeedDate(xmlChildText(root, "updated")),
+		Items:       make([]FeedItem, 0, len(entries)),
+	}
+
+	for _, n := range entries {
+		item := FeedItem{
+			ID:        xmlChildText(n, "id"),
+			Title:     xmlChildText(n, "title"),
+			Link:      atomLink(n),
+			Summary:   xmlChildText(n, "summary"),
+			Content:   xmlChildText(n, "content"),
+			Published: parseFeedDate(xmlChildText(n, "published")),
+			Updated:   parseFeedDate(xmlChildText(n, "updated")),
+		}
+		for _, author := range xmlChildren(n, "author") {
+			item.Authors = append(item.Authors, xmlChildText(author, "name"))
+		}
+		for _, c := range xmlChildren(n, "category") {
+			item.Categories = append(item.Categories, c.SelectAttr("term"))
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	return feed
+}
+
+// atomLink returns the alternate link of an Atom feed or entry.
+func atomLink(n *xmlquery.Node) string {
+	var first string
+	for _, link := range xmlChildren(n, "link") {
+		rel := link.SelectAttr("rel")
+		href := link.SelectAttr("href")
+		if rel == "" || rel == "alternate" {
+			return href
+		}
+		if first == "" {
+			first = href
+		}
+	}
+	return first
+}
+
+// xmlChildren returns the child elements of n with one of the given names,
+// written with their prefix as in "dc:creator".
+func xmlChildren(n *xmlquery.Node, names ...string) []*xmlquery.Node {
+	var children []*xmlquery.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != xmlquery.ElementNode {
+			continue
+		}
+		name := c.Data
+		if c.Prefix != "" {
+			name = c.Prefix + ":" + c.Data
+		}
+		for _, want := range names {
+			if name == want {
+				children = append(children, c)
+				break
+			}
+		}
+	}
+	return children
+}
+
+func xmlChild(n *xmlquery.Node, name string) *xmlquery.Node {
+	if children := xmlChildren(n, name); len(children) > 0 {
+		return children[0]
+	}
+	return nil
+}
+
+// xmlChildText returns the trimmed text of the first non-empty child element
+// with one of the given names, trying the names in order.
+func xmlChildText(n *xmlquery.Node, names ...string) string {
+	for _, name := range names {
+		for _, c := range xmlChildren(n, name) {
+			if text := strings.TrimSpace(c.InnerText()); text != "" {
+				return text
+			}
+		}
+	}
+	return ""
+}
+
+// parseFeedDate parses RSS (RFC 822) and Atom (RFC 3339) dates.
+func parseFeedDate(value string) *time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	layouts := []string{
+		time.RFC1123Z,
+		time.RFC1123,
+		"Mon, 2 Jan 2006 15:04:05 -0700",
+		"Mon, 2 Jan 2006 15:04:05 MST",
+		"2 Jan 2006 15:04:05 -0700",
+		time.RFC822Z,
+		time.RFC822,
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t
+		}
+	}
+	return parseW3CDatetime(value)
+}
@@ -0,0 +1,194 @@
+package scraper
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"web-scraper-api/internal/config"
+)
+
+// Default response limits
+const (
+	defaultMaxBodySize         = 10 << 20
+	defaultMaxDecompressedSize = 50 << 20
+	defaultMaxTextLength       = 1000000
+)
+
+// TruncationReason names the limit that cut a page short.
+type TruncationReason string
+
+const (
+	TruncatedBodySize         TruncationReason = "max_body_size"
+	TruncatedDecompressedSize TruncationReason = "max_decompressed_size"
+	TruncatedTextLength       TruncationReason = "max_text_length"
+)
+
+// responseLimits bounds how much of a response is read and kept.
+type responseLimits struct {
+	bodySize         int64
+	decompressedSize int64
+	textLength       int
+}
+
+// newResponseLimits reads the limits from the configuration, falling back to
+// the defaults for missing or invalid values.
+func newResponseLimits(cfg config.ScrapingConfig) (responseLimits, error) {
+	limits := responseLimits{
+		bodySize:         defaultMaxBodySize,
+		decompressedSize: defaultMaxDecompressedSize,
+		textLength:       defaultMaxTextLength,
+	}
+
+	var err error
+	for _, size := range []struct {
+		value  string
+		target *int64
+	}{
+		{cfg.MaxBodySize, &limits.bodySize},
+		{cfg.MaxDecompressedSize, &limits.decompressedSize},
+	} {
+		if size.value == "" {
+			continue
+		}
+		n, parseErr := config.ParseSize(size.value)
+		if parseErr != nil || n == 0 {
+			err = fmt.Errorf("invalid response size limit %q, using default", size.value)
+			continue
+		}
+		*size.target = n
+	}
+	if cfg.MaxTextLength > 0 {
+		limits.textLength = cfg.MaxTextLength
+	}
+	return limits, err
+}
+
+// limitsFor returns the service limits, lowered by the options where they ask
+// for less. Options cannot raise the configured limits.
+func (s *Service) limitsFor(options *CrawlingOptions) responseLimits {
+	limits := s.limits
+	if options.MaxBodySize > 0 && options.MaxBodySize < limits.bodySize {
+		limits.bodySize = options.MaxBodySize
+	}
+	if options.MaxDecompressedSize > 0 && options.MaxDecompressedSize < limits.decompressedSize {
+		limits.decompressedSize = options.MaxDecompressedSize
+	}
+	if options.MaxTextLength > 0 && options.MaxTextLength < limits.textLength {
+		limits.textLength = options.MaxTextLength
+	}
+	return limits
+}
+
+// limitedReader returns at most n bytes and records whether the underlying
+// reader had more.
+type limitedReader struct {
+	r         io.Reader
+	n         int64
+	truncated bool
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		// Probe for more data so that a body of exactly n bytes is not
+		// reported as truncated
+		var probe [1]byte
+		if n, _ := l.r.Read(probe[:]); n > 0 {
+			l.truncated = true
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// limitedBody reads a response body within the size limits, decoding gzip
+// and deflate content encodings itself so that both the bytes on the wire
+// and the decompressed bytes can be bounded.
+type limitedBody struct {
+	wire         *limitedReader
+	decompressed *limitedReader
+}
+
+func newLimitedBody(resp *http.Response, limits responseLimits) (*limitedBody, error) {
+	body := &limitedBody{wire: &limitedReader{r: resp.Body, n: limits.bodySize}}
+
+	var decoded io.Reader
+	switch encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+		decoded = body.wire
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(body.wire)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		decoded = gz
+	case "deflate":
+		zr, err := zlib.NewReader(body.wire)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deflate body: %w", err)
+		}
+		decoded = zr
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	body.decompressed = &limitedReader{r: decoded, n: limits.decompressedSize}
+	return body, nil
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.decompressed.Read(p)
+	// A compressed stream cut off by the body limit ends early; keep what
+	// was decoded instead of failing the page
+	if err == io.ErrUnexpectedEOF && b.wire.truncated {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// truncation returns the size limits the body ran into.
+func (b *limitedBody) truncation() []TruncationReason {
+	var reasons []TruncationReason
+	if b.wire.truncated {
+		reasons = append(reasons, TruncatedBodySize)
+	}
+	if b.decompressed.truncated {
+		reasons = append(reasons, TruncatedDecompressedSize)
+	}
+	return reasons
+}
+
+// truncateText cuts s to at most n characters.
+func truncateText(s string, n int) (string, bool) {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s, false
+	}
+	i := 0
+	for pos := range s {
+		if i == n {
+			return s[:pos], true
+		}
+		i++
+	}
+	return s, false
+}
+
+// flagTruncation records the size limits the body ran into and cuts the page
+// text to the text length limit.
+func flagTruncation(data *ScrapedData, reasons []TruncationReason, textLength int) {
+	data.TruncationReasons = append(data.TruncationReasons, reasons...)
+	if text, cut := truncateText(data.Text, textLength); cut {
+		data.Text = text
+		data.TruncationReasons = append(data.TruncationReasons, TruncatedTextLength)
+	}
+	data.Truncated = len(data.TruncationReasons) > 0
+}
@@ -0,0 +1,94 @@
+package scraper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newLimitsTestServer(t *testing.T) *httptest.Server {
+	// 10 MB of zeros compress to a few KB
+	var bomb bytes.Buffer
+	gz := gzip.NewWriter(&bomb)
+	gz.Write([]byte("<html><body><p>"))
+	gz.Write(make([]byte, 10<<20))
+	gz.Close()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/large":
+			fmt.Fprint(w, "<html><head><title>Large</title></head><body>")
+			fmt.Fprint(w, strings.Repeat("<p>lorem ipsum</p>", 10000))
+		case "/bomb":
+			if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+				t.Errorf("Scraper should accept gzip, got %q", r.Header.Get("Accept-Encoding"))
+			}
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(bomb.Bytes())
+		case "/exact":
+			fmt.Fprint(w, "<p>1234</p>")
+		}
+	}))
+}
+
+func TestScrape_ResponseLimits(t *testing.T) {
+	server := newLimitsTestServer(t)
+	defer server.Close()
+
+	service := newTestService()
+	ctx := context.Background()
+
+	data, err := service.ScrapeWebsiteWithOptions(ctx, server.URL+"/large", &CrawlingOptions{MaxBodySize: 1024})
+	if err != nil {
+		t.Fatalf("Truncated page should not fail: %v", err)
+	}
+	if !data.Truncated || len(data.TruncationReasons) != 1 || data.TruncationReasons[0] != TruncatedBodySize {
+		t.Errorf("Should be truncated by body size, got %v", data.TruncationReasons)
+	}
+	if data.Title != "Large" || len(data.Text) > 1024 {
+		t.Errorf("Should keep the parsed prefix, got title %q and %d bytes of text", data.Title, len(data.Text))
+	}
+
+	data, err = service.ScrapeWebsiteWithOptions(ctx, server.URL+"/bomb", &CrawlingOptions{MaxDecompressedSize: 64 << 10})
+	if err != nil {
+		t.Fatalf("Compressed page should not fail: %v", err)
+	}
+	if !data.Truncated || data.TruncationReasons[0] != TruncatedDecompressedSize {
+		t.Errorf("Should be truncated by decompressed size, got %v", data.TruncationReasons)
+	}
+	if len(data.Text) > 64<<10 {
+		t.Errorf("Decompressed text should be bounded, got %d bytes", len(data.Text))
+	}
+
+	data, err = service.ScrapeWebsiteWithOptions(ctx, server.URL+"/large", &CrawlingOptions{MaxTextLength: 100})
+	if err != nil {
+		t.Fatalf("Should not return an error: %v", err)
+	}
+	if len([]rune(data.Text)) != 100 || data.TruncationReasons[0] != TruncatedTextLength {
+		t.Errorf("Text should be cut to 100 characters, got %d (%v)", len([]rune(data.Text)), data.TruncationReasons)
+	}
+
+	// A body of exactly the limit is complete
+	data, err = service.ScrapeWebsiteWithOptions(ctx, server.URL+"/exact", &CrawlingOptions{MaxBodySize: int64(len("<p>1234</p>"))})
+	if err != nil {
+		t.Fatalf("Should not return an error: %v", err)
+	}
+	if data.Truncated || data.Text != "1234" {
+		t.Errorf("Body of exactly the limit should not be truncated, got %v %q", data.TruncationReasons, data.Text)
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	if text, cut := truncateText("héllo wörld", 5); !cut || text != "héllo" {
+		t.Errorf("Should cut at 5 characters, got %q", text)
+	}
+	if text, cut := truncateText("short", 10); cut || text != "short" {
+		t.Errorf("Short text should not be cut, got %q", text)
+	}
+}
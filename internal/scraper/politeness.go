@@ -0,0 +1,299 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxConcurrent = 5
+	defaultMaxPerHost    = 2
+	defaultHostRate      = 2.0
+	defaultHostBurst     = 2
+
+	// defaultBackoff is used when a host signals overload without Retry-After
+	defaultBackoff = 5 * time.Second
+	// maxBackoff caps how long a Retry-After header can pause a host
+	maxBackoff = 5 * time.Minute
+	// hostIdleTTL is how long an idle host's state is kept around
+	hostIdleTTL = 10 * time.Minute
+)
+
+// hostState tracks the politeness budget of a single host.
+type hostState struct {
+	slots       chan struct{}
+	tokens      float64
+	rate        float64
+	lastRefill  time.Time
+	lastStart   time.Time
+	crawlDelay  time.Duration
+	pausedUntil time.Time
+	// Requests holding the state, from acquire until they give up or release
+	refs int
+}
+
+// fetchScheduler decides when a request may start. Every host gets a token
+// bucket and a cap on in-flight requests, and all hosts share a global
+// concurrency limit. Hosts that answer 429 or 503 are slowed down.
+type fetchScheduler struct {
+	global      chan struct{}
+	maxPerHost  int
+	rate        float64
+	burst       int
+	mutex       sync.Mutex
+	hosts       map[string]*hostState
+	lastCleanup time.Time
+}
+
+func newFetchScheduler(maxConcurrent, maxPerHost int, rate float64, burst int) *fetchScheduler {
+	if maxConcurrent <= 0 {
+		maxConcurrent = defaultMaxConcurrent
+	}
+	if maxPerHost <= 0 {
+		maxPerHost = defaultMaxPerHost
+	}
+	if rate <= 0 {
+		rate = defaultHostRate
+	}
+	if burst <= 0 {
+		burst = defaultHostBurst
+	}
+
+	return &fetchScheduler{
+		global:      make(chan struct{}, maxConcurrent),
+		maxPerHost:  maxPerHost,
+		rate:        rate,
+		burst:       burst,
+		hosts:       make(map[string]*hostState),
+		lastCleanup: time.Now(),
+	}
+}
+
+func (f *fetchScheduler) host(name string) *hostState {
+	now := time.Now()
+
+	// Drop state of hosts that have been idle for a while
+	if now.Sub(f.lastCleanup) > time.Minute {
+		for key, h := range f.hosts {
+			if h.refs == 0 && now.Sub(h.lastStart) > hostIdleTTL && now.After(h.pausedUntil) {
+				delete(f.hosts, key)
+			}
+		}
+		f.lastCleanup = now
+	}
+
+	h, ok := f.hosts[name]
+	if !ok {
+		h = &hostState{
+			slots:      make(chan struct{}, f.maxPerHost),
+			tokens:     float64(f.burst),
+			rate:       f.rate,
+			lastRefill: now,
+		}
+		f.hosts[name] = h
+	}
+	return h
+}
+
+// setCrawlDelay records the robots.txt Crawl-delay of a host.
+func (f *fetchScheduler) setCrawlDelay(host string, delay time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.host(host).crawlDelay = delay
+}
+
+// reserve books the next start time for a request to h. minInterval is the
+// smallest gap to the previous request the caller asked for.
+func (f *fetchScheduler) reserve(h *hostState, minInterval time.Duration) time.Time {
+	now := time.Now()
+
+	// Refill the token bucket
+	h.tokens += now.Sub(h.lastRefill).Seconds() * h.rate
+	if h.tokens > float64(f.burst) {
+		h.tokens = float64(f.burst)
+	}
+	h.lastRefill = now
+
+	start := now
+	if h.tokens < 1 {
+		start = now.Add(time.Duration((1 - h.tokens) / h.rate * float64(time.Second)))
+	}
+	h.tokens--
+
+	if h.crawlDelay > minInterval {
+		minInterval = h.crawlDelay
+	}
+	if next := h.lastStart.Add(minInterval); next.After(start) {
+		start = next
+	}
+	if h.pausedUntil.After(start) {
+		start = h.pausedUntil
+	}
+
+	h.lastStart = start
+	return start
+}
+
+// fetchPermit is the hold of a request on the global concurrency limit and
+// on the budget of the host it is talking to. Redirects move it from host
+// to host.
+type fetchPermit struct {
+	f           *fetchScheduler
+	host        string
+	h           *hostState
+	minInterval time.Duration
+}
+
+// acquire blocks until a request to host may start. The permit must be
+// released with the response (nil on failure) once it is done.
+func (f *fetchScheduler) acquire(ctx context.Context, host string, minInterval time.Duration) (*fetchPermit, error) {
+	h, err := f.acquireHost(ctx, host, minInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	// Global concurrency cap
+	select {
+	case f.global <- struct{}{}:
+	case <-ctx.Done():
+		f.releaseHost(h, nil)
+		return nil, ctx.Err()
+	}
+
+	return &fetchPermit{f: f, host: host, h: h, minInterval: minInterval}, nil
+}
+
+// acquireHost blocks until host has a free slot and the rate limit lets the
+// request start. The slot is given back with releaseHost.
+func (f *fetchScheduler) acquireHost(ctx context.Context, host string, minInterval time.Duration) (*hostState, error) {
+	f.mutex.Lock()
+	h := f.host(host)
+	h.refs++
+	f.mutex.Unlock()
+
+	// Per-host in-flight cap
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		f.unref(h)
+		return nil, ctx.Err()
+	}
+
+	f.mutex.Lock()
+	start := f.reserve(h, minInterval)
+	f.mutex.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			f.releaseHost(h, nil)
+			return nil, ctx.Err()
+		}
+	}
+	return h, nil
+}
+
+// releaseHost gives back the slot of h and adapts its rate to resp.
+func (f *fetchScheduler) releaseHost(h *hostState, resp *http.Response) {
+	f.mutex.Lock()
+	f.adapt(h, resp)
+	h.refs--
+	f.mutex.Unlock()
+	<-h.slots
+}
+
+// unref drops the hold of a request that gave up on h.
+func (f *fetchScheduler) unref(h *hostState) {
+	f.mutex.Lock()
+	h.refs--
+	f.mutex.Unlock()
+}
+
+// redirect moves the permit to the host of a redirect hop, waiting for its
+// budget like any other request to it. The previous host is given back
+// first, so a request never waits for a host while holding another one.
+func (p *fetchPermit) redirect(ctx context.Context, host string, resp *http.Response) error {
+	if host == p.host {
+		return nil
+	}
+	if p.h != nil {
+		p.f.releaseHost(p.h, resp)
+		p.host, p.h = "", nil
+	}
+
+	h, err := p.f.acquireHost(ctx, host, p.minInterval)
+	if err != nil {
+		return err
+	}
+	p.host, p.h = host, h
+	return nil
+}
+
+// release gives back the permit and adapts the rate of the host that sent
+// resp.
+func (p *fetchPermit) release(resp *http.Response) {
+	if p.h != nil {
+		p.f.releaseHost(p.h, resp)
+		p.host, p.h = "", nil
+	}
+	<-p.f.global
+}
+
+// adapt slows a host down when it signals overload and lets it recover
+// gradually once it answers normally again.
+func (f *fetchScheduler) adapt(h *hostState, resp *http.Response) {
+	if resp == nil {
+		return
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		backoff := parseRetryAfter(resp.Header.Get("Retry-After"))
+		if backoff <= 0 {
+			backoff = defaultBackoff
+		}
+		if until := time.Now().Add(backoff); until.After(h.pausedUntil) {
+			h.pausedUntil = until
+		}
+		h.rate /= 2
+		if floor := f.rate / 16; h.rate < floor {
+			h.rate = floor
+		}
+	default:
+		if h.rate < f.rate {
+			h.rate *= 1.25
+			if h.rate > f.rate {
+				h.rate = f.rate
+			}
+		}
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as
+// an HTTP date. The result is capped at maxBackoff.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	var backoff time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		backoff = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		backoff = time.Until(date)
+	}
+
+	if backoff < 0 {
+		return 0
+	}
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
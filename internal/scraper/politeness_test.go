@@ -0,0 +1,181 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchScheduler_MaxPerHost(t *testing.T) {
+	scheduler := newFetchScheduler(10, 2, 1000, 1000)
+
+	var inFlight, maxInFlight int32
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			permit, err := scheduler.acquire(context.Background(), "example.com", 0)
+			if err != nil {
+				t.Errorf("acquire should not fail: %v", err)
+				return
+			}
+
+			current := atomic.AddInt32(&inFlight, 1)
+			for {
+				peak := atomic.LoadInt32(&maxInFlight)
+				if current <= peak || atomic.CompareAndSwapInt32(&maxInFlight, peak, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+
+			permit.release(nil)
+		}()
+	}
+
+	wg.Wait()
+
+	if maxInFlight > 2 {
+		t.Errorf("Should never exceed 2 requests per host, got %d", maxInFlight)
+	}
+}
+
+func TestFetchScheduler_RetryAfterPausesHost(t *testing.T) {
+	scheduler := newFetchScheduler(10, 2, 1000, 1000)
+
+	permit, err := scheduler.acquire(context.Background(), "slow.example", 0)
+	if err != nil {
+		t.Fatalf("acquire should not fail: %v", err)
+	}
+	permit.release(&http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"1"}},
+	})
+
+	// Other hosts are not affected
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	permit, err = scheduler.acquire(ctx, "fast.example", 0)
+	if err != nil {
+		t.Fatalf("Other hosts should not be paused: %v", err)
+	}
+	permit.release(nil)
+
+	// The throttled host has to wait for Retry-After
+	_, err = scheduler.acquire(ctx, "slow.example", 0)
+	if err == nil {
+		t.Fatal("Throttled host should be paused for the Retry-After duration")
+	}
+}
+
+func TestFetchScheduler_KeepsHostsInUse(t *testing.T) {
+	scheduler := newFetchScheduler(1, 1, 1000, 1000)
+
+	permit, err := scheduler.acquire(context.Background(), "busy.example", 0)
+	if err != nil {
+		t.Fatalf("acquire should not fail: %v", err)
+	}
+
+	// A request that has picked up the host state but not a slot yet
+	waiting := make(chan error)
+	go func() {
+		permit, err := scheduler.acquire(context.Background(), "busy.example", 0)
+		if err == nil {
+			permit.release(nil)
+		}
+		waiting <- err
+	}()
+
+	var state *hostState
+	for state == nil || state.refs < 2 {
+		time.Sleep(time.Millisecond)
+		scheduler.mutex.Lock()
+		state = scheduler.hosts["busy.example"]
+		scheduler.mutex.Unlock()
+	}
+
+	// Age the state so that an idle host would be dropped
+	scheduler.mutex.Lock()
+	state.lastStart = time.Now().Add(-2 * hostIdleTTL)
+	scheduler.lastCleanup = time.Now().Add(-2 * time.Minute)
+	if scheduler.host("busy.example") != state {
+		t.Error("Should keep the state of a host with requests in progress")
+	}
+	scheduler.mutex.Unlock()
+
+	permit.release(nil)
+	if err := <-waiting; err != nil {
+		t.Fatalf("acquire should not fail: %v", err)
+	}
+
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	state.lastStart = time.Now().Add(-2 * hostIdleTTL)
+	scheduler.lastCleanup = time.Now().Add(-2 * time.Minute)
+	if scheduler.host("busy.example") == state {
+		t.Error("Should drop the state of an idle host")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("120"); got != 120*time.Second {
+		t.Errorf("Should parse seconds, got %v", got)
+	}
+	if got := parseRetryAfter("86400"); got != maxBackoff {
+		t.Errorf("Should cap at %v, got %v", maxBackoff, got)
+	}
+	if got := parseRetryAfter("garbage"); got != 0 {
+		t.Errorf("Invalid values should return 0, got %v", got)
+	}
+}
+
+func TestFetchScheduler_RedirectMovesPermit(t *testing.T) {
+	scheduler := newFetchScheduler(10, 1, 1000, 1000)
+
+	permit, err := scheduler.acquire(context.Background(), "a.example", 0)
+	if err != nil {
+		t.Fatalf("acquire should not fail: %v", err)
+	}
+	busy, err := scheduler.acquire(context.Background(), "b.example", 0)
+	if err != nil {
+		t.Fatalf("acquire should not fail: %v", err)
+	}
+
+	// A hop to a host without a free slot waits for it
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := permit.redirect(ctx, "b.example", nil); err == nil {
+		t.Fatal("Should wait for a slot of the host redirected to")
+	}
+
+	// The slot of the previous host is given back on the hop
+	other, err := scheduler.acquire(context.Background(), "a.example", 0)
+	if err != nil {
+		t.Fatalf("Should release the host redirected away from: %v", err)
+	}
+	other.release(nil)
+
+	busy.release(nil)
+	if err := permit.redirect(context.Background(), "b.example", nil); err != nil {
+		t.Fatalf("redirect should not fail: %v", err)
+	}
+	permit.release(nil)
+
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	for name, h := range scheduler.hosts {
+		if h.refs != 0 || len(h.slots) != 0 {
+			t.Errorf("Should release all slots of %s, got %d held", name, len(h.slots))
+		}
+	}
+	if len(scheduler.global) != 0 {
+		t.Errorf("Should release the global slot, got %d held", len(scheduler.global))
+	}
+}
@@ -0,0 +1,415 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"web-scraper-api/internal/config"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ProxyRotation is the strategy for picking a proxy from the pool.
+type ProxyRotation string
+
+const (
+	// ProxyRotationRoundRobin cycles through the healthy proxies
+	ProxyRotationRoundRobin ProxyRotation = "round_robin"
+	// ProxyRotationSticky keeps using the same proxy for a host while it is
+	// healthy
+	ProxyRotationSticky ProxyRotation = "sticky"
+)
+
+const (
+	defaultProxyMaxFailures         = 3
+	defaultProxyHealthCheckURL      = "https://www.google.com/generate_204"
+	defaultProxyHealthCheckInterval = 60 * time.Second
+	proxyHealthCheckTimeout         = 10 * time.Second
+)
+
+// ErrNoHealthyProxy is returned when every proxy of the pool is evicted.
+var ErrNoHealthyProxy = errors.New("no healthy proxy available")
+
+// ProxyStatus describes a pool proxy for monitoring.
+type ProxyStatus struct {
+	URL       string     `json:"url"`
+	Healthy   bool       `json:"healthy"`
+	Failures  int        `json:"failures"`
+	LastError string     `json:"last_error,omitempty"`
+	EvictedAt *time.Time `json:"evicted_at,omitempty"`
+}
+
+// proxyKey carries the proxy chosen for a request in its context.
+type proxyKey struct{}
+
+// trustedDialKey carries the dial address of the trusted proxy a request is
+// sent through.
+type trustedDialKey struct{}
+
+// poolProxy is a proxy of the pool with its health state.
+type poolProxy struct {
+	url       *url.URL
+	healthy   bool
+	failures  int
+	lastError string
+	evictedAt time.Time
+}
+
+// proxyPool rotates requests over a set of proxies, evicting proxies after
+// consecutive failures and restoring them once a health check passes.
+type proxyPool struct {
+	mu          sync.Mutex
+	proxies     []*poolProxy
+	rotation    ProxyRotation
+	maxFailures int
+	next        int
+	sticky      map[string]*poolProxy
+
+	healthURL      string
+	healthInterval time.Duration
+	stop           chan struct{}
+}
+
+func newProxyPool(cfg config.ScrapingConfig) (*proxyPool, error) {
+	pool := &proxyPool{
+		rotation:       ProxyRotation(cfg.ProxyRotation),
+		maxFailures:    cfg.ProxyMaxFailures,
+		sticky:         make(map[string]*poolProxy),
+		healthURL:      cfg.ProxyHealthCheckURL,
+		healthInterval: time.Duration(cfg.ProxyHealthCheckInterval) * time.Second,
+		stop:           make(chan struct{}),
+	}
+	if pool.rotation == "" {
+		pool.rotation = ProxyRotationRoundRobin
+	}
+	if pool.maxFailures <= 0 {
+		pool.maxFailures = defaultProxyMaxFailures
+	}
+	if pool.healthURL == "" {
+		pool.healthURL = defaultProxyHealthCheckURL
+	}
+	if pool.healthInterval <= 0 {
+		pool.healthInterval = defaultProxyHealthCheckInterval
+	}
+
+	if pool.rotation != ProxyRotationRoundRobin && pool.rotation != ProxyRotationSticky {
+		return nil, fmt.Errorf("unknown proxy rotation %q", pool.rotation)
+	}
+	for _, raw := range cfg.Proxies {
+		u, err := parseProxyURL(raw)
+		if err != nil {
+			return nil, err
+		}
+		pool.proxies = append(pool.proxies, &poolProxy{url: u, healthy: true})
+	}
+
+	return pool, nil
+}
+
+// parseProxyURL parses an http, https or socks5 proxy URL with optional
+// credentials.
+func parseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", redactProxy(raw))
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	return u, nil
+}
+
+// redactProxy hides the password of a proxy URL for logs and results.
+func redactProxy(raw string) string {
+	if u, err := url.Parse(raw); err == nil {
+		return u.Redacted()
+	}
+	return "invalid proxy URL"
+}
+
+// pick returns the proxy to use for a request to host.
+func (p *proxyPool) pick(host string) (*poolProxy, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.rotation == ProxyRotationSticky {
+		if proxy, ok := p.sticky[host]; ok && proxy.healthy {
+			return proxy, nil
+		}
+	}
+
+	for i := 0; i < len(p.proxies); i++ {
+		proxy := p.proxies[(p.next+i)%len(p.proxies)]
+		if !proxy.healthy {
+			continue
+		}
+		p.next = (p.next + i + 1) % len(p.proxies)
+		if p.rotation == ProxyRotationSticky {
+			p.sticky[host] = proxy
+		}
+		return proxy, nil
+	}
+	return nil, ErrNoHealthyProxy
+}
+
+// report records the outcome of a request made through proxy. A nil error
+// resets its failure count, consecutive failures evict it.
+func (p *proxyPool) report(proxy *poolProxy, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err == nil {
+		proxy.failures = 0
+		proxy.lastError = ""
+		return
+	}
+
+	proxy.failures++
+	proxy.lastError = err.Error()
+	if proxy.healthy && proxy.failures >= p.maxFailures {
+		proxy.healthy = false
+		proxy.evictedAt = time.Now()
+	}
+}
+
+// restore puts an evicted proxy back into rotation.
+func (p *proxyPool) restore(proxy *poolProxy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	proxy.healthy = true
+	proxy.failures = 0
+	proxy.lastError = ""
+	proxy.evictedAt = time.Time{}
+}
+
+// status returns a snapshot of the pool.
+func (p *proxyPool) status() []ProxyStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	statuses := make([]ProxyStatus, 0, len(p.proxies))
+	for _, proxy := range p.proxies {
+		status := ProxyStatus{
+			URL:       proxy.url.Redacted(),
+			Healthy:   proxy.healthy,
+			Failures:  proxy.failures,
+			LastError: proxy.lastError,
+		}
+		if !proxy.evictedAt.IsZero() {
+			evictedAt := proxy.evictedAt
+			status.EvictedAt = &evictedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// healthLoop checks the evicted proxies periodically until the pool is
+// closed.
+func (p *proxyPool) healthLoop() {
+	ticker := time.NewTicker(p.healthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.checkEvicted()
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+func (p *proxyPool) checkEvicted() {
+	p.mu.Lock()
+	var evicted []*poolProxy
+	for _, proxy := range p.proxies {
+		if !proxy.healthy {
+			evicted = append(evicted, proxy)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, proxy := range evicted {
+		if err := p.healthCheck(proxy); err == nil {
+			p.restore(proxy)
+		} else {
+			p.report(proxy, err)
+		}
+	}
+}
+
+// healthCheck requests the health check URL through the proxy.
+func (p *proxyPool) healthCheck(proxy *poolProxy) error {
+	client := &http.Client{
+		Timeout:   proxyHealthCheckTimeout,
+		Transport: &http.Transport{Proxy: http.ProxyURL(proxy.url)},
+	}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Get(p.healthURL)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("health check returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// proxyChoice is the proxy selected for a request.
+type proxyChoice struct {
+	url    *url.URL
+	pooled *poolProxy
+}
+
+// chooseProxy returns the proxy for a request: the one in the options, or
+// one from the pool. It returns nil when the request goes direct or through
+// the proxy from the environment.
+func (s *Service) chooseProxy(target *url.URL, options *CrawlingOptions) (*proxyChoice, error) {
+	if options.Proxy != "" {
+		u, err := parseProxyURL(options.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		// Proxies supplied by callers must not point into private networks
+		if err := s.urlPolicy.checkHost(u); err != nil {
+			return nil, err
+		}
+		return &proxyChoice{url: u}, nil
+	}
+
+	if len(s.proxies.proxies) == 0 {
+		return nil, nil
+	}
+	proxy, err := s.proxies.pick(target.Host)
+	if err != nil {
+		return nil, err
+	}
+	return &proxyChoice{url: proxy.url, pooled: proxy}, nil
+}
+
+// report passes the outcome of a request to the pool.
+func (c *proxyChoice) report(pool *proxyPool, err error) {
+	if c != nil && c.pooled != nil {
+		pool.report(c.pooled, err)
+	}
+}
+
+// String returns the proxy URL without its password.
+func (c *proxyChoice) String() string {
+	if c == nil {
+		return ""
+	}
+	return c.url.Redacted()
+}
+
+// proxyForRequest is installed as http.Transport.Proxy. It uses the proxy
+// chosen for the request and falls back to the environment.
+func proxyForRequest(req *http.Request) (*url.URL, error) {
+	if choice, ok := req.Context().Value(proxyKey{}).(*proxyChoice); ok && choice != nil {
+		return choice.url, nil
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
+func withProxy(ctx context.Context, choice *proxyChoice) context.Context {
+	return context.WithValue(ctx, proxyKey{}, choice)
+}
+
+// proxyTransport decides the proxy of each request before handing it to the
+// transport. Targets sent through a proxy are resolved and checked against
+// the URL policy, and the dial to a trusted proxy is marked so that only
+// that connection skips the private network check.
+type proxyTransport struct {
+	transport *http.Transport
+	policy    *urlPolicy
+	trusted   map[string]bool
+}
+
+func (t *proxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	proxyURL, err := proxyForRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL != nil {
+		if err := t.policy.checkResolved(req.Context(), req.URL); err != nil {
+			return nil, err
+		}
+		if addr := proxyDialAddr(proxyURL); t.trusted[addr] {
+			req = req.WithContext(context.WithValue(req.Context(), trustedDialKey{}, addr))
+		}
+	}
+	return t.transport.RoundTrip(req)
+}
+
+func (t *proxyTransport) CloseIdleConnections() {
+	t.transport.CloseIdleConnections()
+}
+
+// isTrustedDial reports whether ctx belongs to a request sent through the
+// trusted proxy at addr.
+func isTrustedDial(ctx context.Context, addr string) bool {
+	trusted, _ := ctx.Value(trustedDialKey{}).(string)
+	return trusted != "" && trusted == strings.ToLower(addr)
+}
+
+// trustedProxyAddrs returns the dial addresses of the configured proxies
+// and the proxies from the environment. Connections made to them as proxies
+// skip the private network check of the URL policy, proxies in requests do
+// not.
+func trustedProxyAddrs(pool *proxyPool) map[string]bool {
+	addrs := make(map[string]bool)
+	add := func(u *url.URL) {
+		addrs[proxyDialAddr(u)] = true
+	}
+
+	for _, proxy := range pool.proxies {
+		add(proxy.url)
+	}
+	env := httpproxy.FromEnvironment()
+	for _, raw := range []string{env.HTTPProxy, env.HTTPSProxy} {
+		if raw == "" {
+			continue
+		}
+		if !strings.Contains(raw, "://") {
+			raw = "http://" + raw
+		}
+		if u, err := url.Parse(raw); err == nil && u.Host != "" {
+			add(u)
+		}
+	}
+	return addrs
+}
+
+// proxyDialAddr returns the host:port the transport dials for a proxy.
+func proxyDialAddr(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(strings.ToLower(u.Hostname()), port)
+}
+
+// ProxyPoolStatus returns the health of the proxy pool.
+func (s *Service) ProxyPoolStatus() []ProxyStatus {
+	return s.proxies.status()
+}
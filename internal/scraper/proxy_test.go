@@ -0,0 +1,191 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"web-scraper-api/internal/config"
+	"web-scraper-api/internal/logger"
+)
+
+// newForwardProxy starts an HTTP proxy that answers every request itself,
+// reporting the URL it was asked for.
+func newForwardProxy(name string, hits *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><head><title>%s %s</title></head></html>", name, r.URL.String())
+	}))
+}
+
+// staticResolver resolves hosts from a fixed table.
+type staticResolver map[string]string
+
+func (r staticResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	addr, ok := r[host]
+	if !ok {
+		return nil, fmt.Errorf("no such host %s", host)
+	}
+	return []netip.Addr{netip.MustParseAddr(addr)}, nil
+}
+
+func TestScrape_PerRequestProxy(t *testing.T) {
+	var hits int32
+	proxy := newForwardProxy("proxy", &hits)
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	proxyURL.User = url.UserPassword("user", "secret")
+
+	service := newTestService()
+	data, err := service.ScrapeWebsiteWithOptions(context.Background(), "http://example.test/page", &CrawlingOptions{Proxy: proxyURL.String()})
+	if err != nil {
+		t.Fatalf("Should not return an error: %v", err)
+	}
+	if hits != 1 || data.Title != "proxy http://example.test/page" {
+		t.Errorf("Request should go through the proxy, got %q", data.Title)
+	}
+	if strings.Contains(data.Proxy, "secret") || !strings.Contains(data.Proxy, "user") {
+		t.Errorf("Result should record the proxy without its password, got %q", data.Proxy)
+	}
+
+	if err := (&CrawlingOptions{Proxy: "ftp://proxy.example.com"}).Validate(); err == nil {
+		t.Error("Should reject unsupported proxy schemes")
+	}
+
+	// Without private networks, a request proxy on loopback is refused
+	strict := NewServiceWithConfig(&config.Config{}, logger.New("error"))
+	_, err = strict.ScrapeWebsiteWithOptions(context.Background(), "http://example.test/page", &CrawlingOptions{Proxy: proxy.URL})
+	if ErrorKindOf(err) != ErrorKindPolicy || blockReason(err) != BlockReasonPrivateNetwork {
+		t.Errorf("Should block private request proxies, got %v", err)
+	}
+}
+
+func TestScrape_ProxyPoolRotation(t *testing.T) {
+	var hitsA, hitsB int32
+	proxyA := newForwardProxy("a", &hitsA)
+	defer proxyA.Close()
+	proxyB := newForwardProxy("b", &hitsB)
+	defer proxyB.Close()
+
+	// Configured proxies are trusted even though they are on loopback
+	service := NewServiceWithConfig(&config.Config{
+		Scraping: config.ScrapingConfig{Proxies: []string{proxyA.URL, proxyB.URL}},
+	}, logger.New("error"))
+	defer service.Close()
+	service.urlPolicy.resolver = staticResolver{"example.test": "93.184.216.34"}
+
+	for i := 0; i < 4; i++ {
+		data, err := service.ScrapeWebsiteWithOptions(context.Background(), "http://example.test/", &CrawlingOptions{})
+		if err != nil {
+			t.Fatalf("Should not return an error: %v", err)
+		}
+		if data.Proxy == "" {
+			t.Error("Result should record the pool proxy")
+		}
+	}
+	if hitsA != 2 || hitsB != 2 {
+		t.Errorf("Round robin should spread requests evenly, got %d and %d", hitsA, hitsB)
+	}
+}
+
+func TestScrape_ProxyDoesNotBypassPolicy(t *testing.T) {
+	var hits int32
+	proxy := newForwardProxy("proxy", &hits)
+	defer proxy.Close()
+
+	service := NewServiceWithConfig(&config.Config{
+		Scraping: config.ScrapingConfig{Proxies: []string{proxy.URL}},
+	}, logger.New("error"))
+	defer service.Close()
+	service.urlPolicy.resolver = staticResolver{"internal.test": "10.1.2.3"}
+
+	// The proxy would resolve the target itself, so it is checked first
+	_, err := service.ScrapeWebsiteWithOptions(context.Background(), "http://internal.test/", &CrawlingOptions{})
+	if blockReason(err) != BlockReasonPrivateNetwork {
+		t.Errorf("Should block targets resolving to private addresses, got %v", err)
+	}
+
+	// Dialing the proxy address directly, not as a proxy, is not trusted
+	req, _ := http.NewRequest("GET", proxy.URL, nil)
+	req = req.WithContext(withProxy(req.Context(), nil))
+	resp, err := service.client.Transport.RoundTrip(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	if blockReason(err) != BlockReasonPrivateNetwork {
+		t.Errorf("Should check direct connections to a proxy address, got %v", err)
+	}
+	if hits != 0 {
+		t.Errorf("No request should reach the proxy, got %d", hits)
+	}
+}
+
+func TestProxyPool_StickyAndEviction(t *testing.T) {
+	pool, err := newProxyPool(config.ScrapingConfig{
+		Proxies:          []string{"http://a.example.com:3128", "socks5://b.example.com"},
+		ProxyRotation:    string(ProxyRotationSticky),
+		ProxyMaxFailures: 2,
+	})
+	if err != nil {
+		t.Fatalf("Should create the pool: %v", err)
+	}
+
+	first, _ := pool.pick("example.com")
+	other, _ := pool.pick("example.org")
+	if again, _ := pool.pick("example.com"); again != first || other == first {
+		t.Fatal("Sticky rotation should keep the proxy per host")
+	}
+
+	pool.report(first, fmt.Errorf("connection refused"))
+	if again, _ := pool.pick("example.com"); again != first {
+		t.Fatal("Should keep a proxy below the failure limit")
+	}
+	pool.report(first, fmt.Errorf("connection refused"))
+	if again, _ := pool.pick("example.com"); again == first {
+		t.Fatal("Should evict a proxy after consecutive failures")
+	}
+	if status := pool.status(); status[0].Healthy || status[0].EvictedAt == nil {
+		t.Errorf("Status should report the eviction, got %+v", status[0])
+	}
+
+	pool.report(other, fmt.Errorf("timeout"))
+	pool.report(other, fmt.Errorf("timeout"))
+	if _, err := pool.pick("example.net"); err != ErrNoHealthyProxy {
+		t.Errorf("Should fail without healthy proxies, got %v", err)
+	}
+
+	pool.restore(first)
+	if again, _ := pool.pick("example.com"); again != first {
+		t.Error("Restored proxies should rejoin the rotation")
+	}
+
+	if _, err := newProxyPool(config.ScrapingConfig{ProxyRotation: "random"}); err == nil {
+		t.Error("Should reject unknown rotation strategies")
+	}
+}
+
+func TestProxyPool_HealthCheckRestores(t *testing.T) {
+	var hits int32
+	proxy := newForwardProxy("health", &hits)
+	defer proxy.Close()
+
+	pool, _ := newProxyPool(config.ScrapingConfig{
+		Proxies:             []string{proxy.URL},
+		ProxyMaxFailures:    1,
+		ProxyHealthCheckURL: "http://example.test/generate_204",
+	})
+	pool.report(pool.proxies[0], fmt.Errorf("connection refused"))
+
+	pool.checkEvicted()
+	if !pool.proxies[0].healthy || hits != 1 {
+		t.Error("A passing health check should restore the proxy")
+	}
+}
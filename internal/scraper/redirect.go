@@ -0,0 +1,84 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// defaultMaxRedirects matches the limit of net/http's default client.
+const defaultMaxRedirects = 10
+
+// ErrTooManyRedirects is returned when a request exceeds its redirect limit.
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+type RedirectHop struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+}
+
+// redirectPolicy is attached to a request context and consulted by the shared
+// client's CheckRedirect hook, so that each request can have its own policy.
+type redirectPolicy struct {
+	follow       bool
+	maxRedirects int
+	hops         []RedirectHop
+	// Politeness budget of the request, moved to the host of each hop
+	permit *fetchPermit
+}
+
+type redirectPolicyKey struct{}
+
+// newRedirectPolicy derives the redirect policy for a request. The service
+// configuration caps what the options may ask for and applies when they
+// don't say whether to follow redirects.
+func (s *Service) newRedirectPolicy(options *CrawlingOptions) *redirectPolicy {
+	policy := &redirectPolicy{
+		follow:       s.followRedirects,
+		maxRedirects: s.maxRedirects,
+	}
+	if options.FollowRedirects != nil && !*options.FollowRedirects {
+		policy.follow = false
+	}
+	if options.MaxRedirects > 0 && options.MaxRedirects < policy.maxRedirects {
+		policy.maxRedirects = options.MaxRedirects
+	}
+	return policy
+}
+
+func withRedirectPolicy(ctx context.Context, policy *redirectPolicy) context.Context {
+	return context.WithValue(ctx, redirectPolicyKey{}, policy)
+}
+
+// checkRedirect is installed as http.Client.CheckRedirect. It records every
+// hop in the request's redirect policy and enforces its limits.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	policy, _ := req.Context().Value(redirectPolicyKey{}).(*redirectPolicy)
+	if policy == nil {
+		if len(via) >= defaultMaxRedirects {
+			return fmt.Errorf("stopped after %d redirects: %w", len(via), ErrTooManyRedirects)
+		}
+		return nil
+	}
+
+	if req.Response != nil {
+		policy.hops = append(policy.hops, RedirectHop{
+			URL:        via[len(via)-1].URL.String(),
+			StatusCode: req.Response.StatusCode,
+			Location:   req.Response.Header.Get("Location"),
+		})
+	}
+
+	if !policy.follow {
+		return http.ErrUseLastResponse
+	}
+	if len(via) > policy.maxRedirects {
+		return fmt.Errorf("stopped after %d redirects: %w", policy.maxRedirects, ErrTooManyRedirects)
+	}
+	if policy.permit != nil {
+		return policy.permit.redirect(req.Context(), req.URL.Host, req.Response)
+	}
+	return nil
+}
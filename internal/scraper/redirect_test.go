@@ -0,0 +1,163 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func newRedirectTestServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/middle", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/middle", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/final", http.StatusFound)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><head><title>final</title></head></html>")
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestScrapeWebsite_RecordsRedirectChain(t *testing.T) {
+	server := newRedirectTestServer()
+	defer server.Close()
+
+	service := newTestService()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	data, err := service.ScrapeWebsiteWithOptions(ctx, server.URL+"/start", &CrawlingOptions{})
+	if err != nil {
+		t.Fatalf("Should not return an error: %v", err)
+	}
+
+	if data.URL != server.URL+"/final" {
+		t.Errorf("URL should be the final URL, got '%s'", data.URL)
+	}
+	if data.RequestedURL != server.URL+"/start" {
+		t.Errorf("RequestedURL should be the original URL, got '%s'", data.RequestedURL)
+	}
+	if len(data.RedirectChain) != 2 {
+		t.Fatalf("Should record 2 redirect hops, got %d", len(data.RedirectChain))
+	}
+	if data.RedirectChain[0].StatusCode != http.StatusMovedPermanently || data.RedirectChain[0].Location != "/middle" {
+		t.Errorf("Unexpected first hop: %+v", data.RedirectChain[0])
+	}
+	if data.RedirectChain[1].URL != server.URL+"/middle" || data.RedirectChain[1].StatusCode != http.StatusFound {
+		t.Errorf("Unexpected second hop: %+v", data.RedirectChain[1])
+	}
+}
+
+func TestScrapeWebsite_FollowsRedirectsByDefault(t *testing.T) {
+	server := newRedirectTestServer()
+	defer server.Close()
+
+	service := newTestService()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// A request that leaves out follow_redirects
+	var options CrawlingOptions
+	if err := json.Unmarshal([]byte(`{"max_redirects": 5}`), &options); err != nil {
+		t.Fatalf("Failed to decode options: %v", err)
+	}
+	data, err := service.ScrapeWebsiteWithOptions(ctx, server.URL+"/start", &options)
+	if err != nil {
+		t.Fatalf("Should not return an error: %v", err)
+	}
+	if data.URL != server.URL+"/final" {
+		t.Errorf("Should follow redirects as configured for the service, got '%s'", data.URL)
+	}
+}
+
+func TestScrapeWebsite_DoesNotFollowRedirects(t *testing.T) {
+	server := newRedirectTestServer()
+	defer server.Close()
+
+	service := newTestService()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	follow := false
+	data, err := service.ScrapeWebsiteWithOptions(ctx, server.URL+"/start", &CrawlingOptions{FollowRedirects: &follow})
+	if err != nil {
+		t.Fatalf("Should not return an error: %v", err)
+	}
+
+	if data.StatusCode != http.StatusMovedPermanently {
+		t.Errorf("Status Code should be 301, got %d", data.StatusCode)
+	}
+	if data.URL != server.URL+"/start" {
+		t.Errorf("URL should stay at the requested URL, got '%s'", data.URL)
+	}
+	if len(data.RedirectChain) != 1 || data.RedirectChain[0].Location != "/middle" {
+		t.Errorf("Should record the unfollowed redirect, got %+v", data.RedirectChain)
+	}
+}
+
+func TestScrapeWebsite_MaxRedirects(t *testing.T) {
+	server := newRedirectTestServer()
+	defer server.Close()
+
+	service := newTestService()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := service.ScrapeWebsiteWithOptions(ctx, server.URL+"/start", &CrawlingOptions{MaxRedirects: 1})
+	if !errors.Is(err, ErrTooManyRedirects) {
+		t.Fatalf("Should return ErrTooManyRedirects, got %v", err)
+	}
+}
+
+func TestScrapeWebsite_RedirectWaitsForTargetHost(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><head><title>target</title></head></html>")
+	}))
+	defer target.Close()
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusFound)
+	}))
+	defer origin.Close()
+
+	service := newTestService()
+	targetURL, _ := url.Parse(target.URL)
+
+	// Take all slots of the target host
+	var permits []*fetchPermit
+	for i := 0; i < defaultMaxPerHost; i++ {
+		permit, err := service.fetches.acquire(context.Background(), targetURL.Host, 0)
+		if err != nil {
+			t.Fatalf("acquire should not fail: %v", err)
+		}
+		permits = append(permits, permit)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := service.ScrapeWebsiteWithOptions(ctx, origin.URL, &CrawlingOptions{}); err == nil {
+		t.Fatal("Should wait for a slot of the host redirected to")
+	}
+
+	for _, permit := range permits {
+		permit.release(nil)
+	}
+	data, err := service.ScrapeWebsiteWithOptions(context.Background(), origin.URL, &CrawlingOptions{})
+	if err != nil {
+		t.Fatalf("Should not return an error: %v", err)
+	}
+	if data.URL != target.URL {
+		t.Errorf("URL should be the final URL, got '%s'", data.URL)
+	}
+}
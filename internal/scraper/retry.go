@@ -0,0 +1,276 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRetryAttempts    = 3
+	defaultRetryBaseBackoff = 500 * time.Millisecond
+	defaultRetryMaxBackoff  = 30 * time.Second
+	defaultRetryJitter      = 0.2
+)
+
+var (
+	defaultRetryableStatusCodes = []int{
+		http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	}
+	defaultRetryableErrors = []ErrorKind{ErrorKindNetwork, ErrorKindTimeout}
+)
+
+// RetryPolicy configures how failed requests are retried. Zero values are
+// replaced by the defaults; a nil policy disables retries.
+type RetryPolicy struct {
+	MaxAttempts int           `json:"max_attempts"`
+	BaseBackoff time.Duration `json:"base_backoff"`
+	MaxBackoff  time.Duration `json:"max_backoff"`
+	// Jitter randomizes each backoff by up to this fraction (0-1), 0.2
+	// when unset and none when 0
+	Jitter               *float64    `json:"jitter,omitempty"`
+	RetryableStatusCodes []int       `json:"retryable_status_codes,omitempty"`
+	RetryableErrors      []ErrorKind `json:"retryable_errors,omitempty"`
+}
+
+// Validate checks the retry settings. Zero values are valid and mean the
+// defaults.
+func (p *RetryPolicy) Validate() error {
+	if p.MaxAttempts < 0 || p.BaseBackoff < 0 || p.MaxBackoff < 0 {
+		return fmt.Errorf("retry settings must not be negative")
+	}
+	if p.Jitter != nil && (*p.Jitter < 0 || *p.Jitter > 1) {
+		return fmt.Errorf("retry jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// withDefaults returns a copy of the policy with all unset fields filled in.
+func (p *RetryPolicy) withDefaults() *RetryPolicy {
+	if p == nil {
+		return &RetryPolicy{MaxAttempts: 1}
+	}
+
+	policy := *p
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = defaultRetryAttempts
+	}
+	if policy.BaseBackoff <= 0 {
+		policy.BaseBackoff = defaultRetryBaseBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = defaultRetryMaxBackoff
+	}
+	if policy.Jitter == nil {
+		jitter := defaultRetryJitter
+		policy.Jitter = &jitter
+	}
+	if policy.RetryableStatusCodes == nil {
+		policy.RetryableStatusCodes = defaultRetryableStatusCodes
+	}
+	if policy.RetryableErrors == nil {
+		policy.RetryableErrors = defaultRetryableErrors
+	}
+	return &policy
+}
+
+func (p *RetryPolicy) retryableStatus(code int) bool {
+	for _, c := range p.RetryableStatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) retryableError(kind ErrorKind) bool {
+	for _, k := range p.RetryableErrors {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the wait before the given retry (1 for the first retry):
+// exponential growth from BaseBackoff, capped at MaxBackoff, with jitter.
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	d := float64(p.BaseBackoff) * math.Pow(2, float64(retry-1))
+	if d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	d *= 1 - *p.Jitter*rand.Float64()
+	return time.Duration(d)
+}
+
+// fetchResult is a response together with the bookkeeping of how it was
+// obtained. release must be called once the body has been consumed.
+type fetchResult struct {
+	resp       *http.Response
+	release    func(*http.Response)
+	redirects  *redirectPolicy
+	attempts   int
+	proxy      string
+	setCookies []Cookie
+}
+
+// fetch performs the GET request for rawURL, retrying transient failures as
+// configured by options.Retry.
+func (s *Service) fetch(ctx context.Context, rawURL string, options *CrawlingOptions) (*fetchResult, error) {
+	policy := options.Retry.withDefaults()
+
+	for attempt := 1; ; attempt++ {
+		result, err := s.fetchOnce(ctx, rawURL, nil, options)
+
+		var retryable bool
+		if err == nil {
+			// Only an explicit retry policy turns an error status into a failure
+			if options.Retry == nil || !policy.retryableStatus(result.resp.StatusCode) {
+				result.attempts = attempt
+				return result, nil
+			}
+
+			result.resp.Body.Close()
+			result.release(result.resp)
+			err = &ScrapeError{
+				Kind:       ErrorKindHTTPStatus,
+				URL:        rawURL,
+				StatusCode: result.resp.StatusCode,
+			}
+			retryable = true
+		} else {
+			retryable = policy.retryableError(ErrorKindOf(err))
+		}
+
+		var scrapeErr *ScrapeError
+		if errors.As(err, &scrapeErr) {
+			scrapeErr.Attempts = attempt
+		}
+
+		if !retryable || attempt >= policy.MaxAttempts || ctx.Err() != nil {
+			return nil, err
+		}
+
+		wait := policy.backoff(attempt)
+		s.logger.Warnf("Attempt %d for %s failed, retrying in %v: %v", attempt, rawURL, wait, err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		}
+	}
+}
+
+// fetchOnce performs a single attempt of the request for rawURL: a GET, or
+// a POST of form when it is not nil.
+func (s *Service) fetchOnce(ctx context.Context, rawURL string, form url.Values, options *CrawlingOptions) (*fetchResult, error) {
+	if err := s.checkURL(rawURL); err != nil {
+		return nil, err
+	}
+
+	// Attach the redirect policy for this request
+	redirects := s.newRedirectPolicy(options)
+	ctx = withRedirectPolicy(ctx, redirects)
+
+	// Create HTTP request
+	method, body := http.MethodGet, io.Reader(nil)
+	if form != nil {
+		method, body = http.MethodPost, strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return nil, &ScrapeError{Kind: ErrorKindPolicy, URL: rawURL, Err: err}
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	// Route the request through the proxy of the options or the pool
+	proxy, err := s.chooseProxy(req.URL, options)
+	if err != nil {
+		kind := ErrorKindPolicy
+		if errors.Is(err, ErrNoHealthyProxy) {
+			kind = ErrorKindNetwork
+		}
+		return nil, &ScrapeError{Kind: kind, URL: rawURL, Err: err}
+	}
+	req = req.WithContext(withProxy(ctx, proxy))
+
+	// Set User-Agent
+	req.Header.Set("User-Agent", userAgentFor(options))
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+
+	// Set custom headers
+	for key, value := range options.Headers {
+		req.Header.Set(key, value)
+	}
+
+	// Credentials of the auth section override the custom headers
+	if err := s.applyAuth(ctx, req); err != nil {
+		return nil, &ScrapeError{Kind: ErrorKindAuth, URL: rawURL, Err: err}
+	}
+
+	// Wait for the host's politeness budget; Delay is the minimum gap to the
+	// previous request to the same host
+	permit, err := s.fetches.acquire(ctx, req.URL.Host, options.Delay)
+	if err != nil {
+		return nil, &ScrapeError{Kind: classifyRequestError(err), URL: rawURL, Err: err}
+	}
+	redirects.permit = permit
+
+	// Send and collect cookies through the jar of the scrape or crawl
+	client := s.client
+	var cookies *cookieRecorder
+	if jar := cookieJarFrom(ctx); jar != nil {
+		cookies = &cookieRecorder{CookieJar: jar}
+		withJar := *s.client
+		withJar.Jar = cookies
+		client = &withJar
+	}
+
+	// Execute request
+	resp, err := client.Do(req)
+	if err != nil {
+		// Errors caused by the target or the caller do not count against the proxy
+		if kind := classifyRequestError(err); ctx.Err() == nil && (kind == ErrorKindNetwork || kind == ErrorKindTimeout) {
+			proxy.report(s.proxies, err)
+		}
+		permit.release(nil)
+		return nil, &ScrapeError{Kind: classifyRequestError(err), URL: rawURL, Err: err}
+	}
+	if resp.StatusCode == http.StatusProxyAuthRequired {
+		proxy.report(s.proxies, fmt.Errorf("proxy returned status %d", resp.StatusCode))
+	} else {
+		proxy.report(s.proxies, nil)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		// Fetch a new access token for the next attempt
+		s.invalidateAuth(ctx)
+	}
+
+	result := &fetchResult{
+		resp:      resp,
+		release:   permit.release,
+		redirects: redirects,
+		proxy:     proxy.String(),
+	}
+	if cookies != nil {
+		result.setCookies = cookies.set
+	}
+	return result, nil
+}
@@ -0,0 +1,135 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScrapeWebsite_RetriesTransientStatus(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, "<html><head><title>ok</title></head></html>")
+	}))
+	defer server.Close()
+
+	service := newTestService()
+	options := &CrawlingOptions{
+		Retry: &RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	data, err := service.ScrapeWebsiteWithOptions(ctx, server.URL, options)
+	if err != nil {
+		t.Fatalf("Should succeed after retries: %v", err)
+	}
+	if data.Attempts != 3 {
+		t.Errorf("Should take 3 attempts, got %d", data.Attempts)
+	}
+}
+
+func TestScrapeWebsite_RetryExhausted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	service := newTestService()
+	options := &CrawlingOptions{
+		Retry: &RetryPolicy{MaxAttempts: 2, BaseBackoff: time.Millisecond},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	_, err := service.ScrapeWebsiteWithOptions(ctx, server.URL, options)
+
+	var scrapeErr *ScrapeError
+	if !errors.As(err, &scrapeErr) {
+		t.Fatalf("Should return a *ScrapeError, got %v", err)
+	}
+	if scrapeErr.Kind != ErrorKindHTTPStatus || scrapeErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Unexpected error: %v", scrapeErr)
+	}
+	if scrapeErr.Attempts != 2 {
+		t.Errorf("Should report 2 attempts, got %d", scrapeErr.Attempts)
+	}
+}
+
+func TestScrapeWebsite_NoRetryWithoutPolicy(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	service := newTestService()
+
+	data, err := service.ScrapeWebsiteWithOptions(context.Background(), server.URL, &CrawlingOptions{})
+	if err != nil {
+		t.Fatalf("Should not return an error: %v", err)
+	}
+	if data.StatusCode != http.StatusBadGateway || data.Attempts != 1 || requests != 1 {
+		t.Errorf("Should make a single attempt, got status %d after %d attempts", data.StatusCode, data.Attempts)
+	}
+}
+
+func TestScrapeWebsite_NetworkErrorKind(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	service := newTestService()
+
+	_, err := service.ScrapeWebsiteWithOptions(context.Background(), url, &CrawlingOptions{})
+	if kind := ErrorKindOf(err); kind != ErrorKindNetwork {
+		t.Errorf("Error kind should be '%s', got '%s'", ErrorKindNetwork, kind)
+	}
+
+	_, err = service.ScrapeWebsiteWithOptions(context.Background(), "invalid-url", &CrawlingOptions{})
+	if kind := ErrorKindOf(err); kind != ErrorKindPolicy {
+		t.Errorf("Error kind should be '%s', got '%s'", ErrorKindPolicy, kind)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	jitter := 0.5
+	policy := (&RetryPolicy{BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Jitter: &jitter}).withDefaults()
+
+	for retry, limit := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 5: time.Second} {
+		d := policy.backoff(retry)
+		if d > limit || d < limit/2 {
+			t.Errorf("Backoff for retry %d should be within [%v, %v], got %v", retry, limit/2, limit, d)
+		}
+	}
+}
+
+func TestRetryPolicy_Jitter(t *testing.T) {
+	if jitter := *(&RetryPolicy{}).withDefaults().Jitter; jitter != defaultRetryJitter {
+		t.Errorf("Expected the default jitter when unset, got %v", jitter)
+	}
+
+	none := 0.0
+	policy := (&RetryPolicy{BaseBackoff: 100 * time.Millisecond, Jitter: &none}).withDefaults()
+	if d := policy.backoff(2); d != 200*time.Millisecond {
+		t.Errorf("Should not randomize the backoff with a jitter of 0, got %v", d)
+	}
+
+	for _, jitter := range []float64{-0.1, 1.5} {
+		if err := (&RetryPolicy{Jitter: &jitter}).Validate(); err == nil {
+			t.Errorf("Should reject a jitter of %v", jitter)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// robotsCacheTTL is how long a fetched robots.txt is reused (RFC 9309 caps it at 24h)
+	robotsCacheTTL = 24 * time.Hour
+	// robotsErrorTTL is how long an unreachable robots.txt is remembered before retrying
+	robotsErrorTTL = 5 * time.Minute
+	// robotsMaxSize is the maximum number of bytes parsed from a robots.txt
+	robotsMaxSize = 500 * 1024
+	// robotsFetchTimeout bounds the robots.txt request independently of the
+	// page request
+	robotsFetchTimeout = 10 * time.Second
+)
+
+// robotsRule is a single Allow or Disallow line.
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// robotsGroup holds the rules for a set of user agents.
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// RobotsTxt is a parsed robots.txt file.
+type RobotsTxt struct {
+	groups   []*robotsGroup
+	Sitemaps []string
+	// disallowAll is set when the file could not be fetched because of a
+	// server or network error; RFC 9309 requires assuming a full disallow.
+	disallowAll bool
+}
+
+// ParseRobotsTxt parses the content of a robots.txt file. Unknown lines are
+// ignored, consecutive user-agent lines share one group.
+func ParseRobotsTxt(r io.Reader) *RobotsTxt {
+	robots := &RobotsTxt{}
+
+	var current *robotsGroup
+	lastWasAgent := false
+
+	scanner := bufio.NewScanner(io.LimitReader(r, robotsMaxSize))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if current == nil || !lastWasAgent {
+				current = &robotsGroup{}
+				robots.groups = append(robots.groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			lastWasAgent = true
+			continue
+		case "allow", "disallow":
+			// An empty Disallow allows everything and can be ignored
+			if current != nil && value != "" {
+				current.rules = append(current.rules, robotsRule{pattern: value, allow: key == "allow"})
+			}
+		case "crawl-delay":
+			if current != nil {
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					current.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		case "sitemap":
+			if value != "" {
+				robots.Sitemaps = append(robots.Sitemaps, value)
+			}
+		}
+		lastWasAgent = false
+	}
+
+	return robots
+}
+
+// userAgentTokens returns the lowercased product tokens of a user agent
+// string, including those inside comments, such as "googlebot" and "2.1" in
+// "Mozilla/5.0 (compatible; Googlebot/2.1)".
+func userAgentTokens(userAgent string) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, token := range strings.FieldsFunc(strings.ToLower(userAgent), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_')
+	}) {
+		tokens[token] = true
+	}
+	return tokens
+}
+
+// matchGroups returns the groups that apply to userAgent: all groups naming
+// the longest agent that is one of its product tokens, or the "*" groups if
+// none matches.
+func (r *RobotsTxt) matchGroups(userAgent string) []*robotsGroup {
+	tokens := userAgentTokens(userAgent)
+
+	var matched, wildcard []*robotsGroup
+	bestLen := 0
+
+	for _, group := range r.groups {
+		for _, agent := range group.agents {
+			switch {
+			case agent == "*":
+				wildcard = append(wildcard, group)
+			case tokens[agent]:
+				if len(agent) > bestLen {
+					bestLen = len(agent)
+					matched = matched[:0]
+				}
+				if len(agent) == bestLen {
+					matched = append(matched, group)
+				}
+			default:
+				continue
+			}
+			break
+		}
+	}
+
+	if len(matched) > 0 {
+		return matched
+	}
+	return wildcard
+}
+
+// Allowed reports whether userAgent may fetch the given path (including the
+// query string). The most specific matching rule wins; on a tie Allow wins.
+func (r *RobotsTxt) Allowed(userAgent, path string) bool {
+	if r.disallowAll {
+		return false
+	}
+	if path == "" {
+		path = "/"
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+
+	bestLen := -1
+	allowed := true
+
+	for _, group := range r.matchGroups(userAgent) {
+		for _, rule := range group.rules {
+			if !robotsPatternMatch(rule.pattern, path) {
+				continue
+			}
+			if len(rule.pattern) > bestLen || (len(rule.pattern) == bestLen && rule.allow) {
+				bestLen = len(rule.pattern)
+				allowed = rule.allow
+			}
+		}
+	}
+
+	return allowed
+}
+
+// CrawlDelay returns the Crawl-delay that applies to userAgent, or zero.
+func (r *RobotsTxt) CrawlDelay(userAgent string) time.Duration {
+	var delay time.Duration
+	for _, group := range r.matchGroups(userAgent) {
+		if group.crawlDelay > delay {
+			delay = group.crawlDelay
+		}
+	}
+	return delay
+}
+
+// robotsPatternMatch matches a robots.txt path pattern where "*" matches any
+// sequence of characters and a trailing "$" anchors the pattern at the end.
+func robotsPatternMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+
+	parts := strings.Split(pattern, "*")
+
+	// The first part is a prefix of the path
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+
+	for i := 1; i < len(parts); i++ {
+		part := parts[i]
+		if i == len(parts)-1 && anchored {
+			return len(path)-pos >= len(part) && strings.HasSuffix(path, part)
+		}
+		idx := strings.Index(path[pos:], part)
+		if idx < 0 {
+			return false
+		}
+		pos += idx + len(part)
+	}
+
+	if anchored {
+		return pos == len(path)
+	}
+	return true
+}
+
+type robotsEntry struct {
+	robots    *RobotsTxt
+	expiresAt time.Time
+}
+
+// robotsFetch is a fetch of robots.txt in progress. Lookups of the same
+// origin wait for it instead of fetching the file again.
+type robotsFetch struct {
+	done   chan struct{}
+	robots *RobotsTxt
+	err    error
+}
+
+// robotsCache fetches robots.txt once per host and keeps it until it expires.
+type robotsCache struct {
+	client   *http.Client
+	mutex    sync.Mutex
+	entries  map[string]*robotsEntry
+	fetching map[string]*robotsFetch
+}
+
+func newRobotsCache(client *http.Client) *robotsCache {
+	return &robotsCache{
+		client:   client,
+		entries:  make(map[string]*robotsEntry),
+		fetching: make(map[string]*robotsFetch),
+	}
+}
+
+// get returns the robots.txt for the scheme and host of u, fetching it if it
+// is not cached or has expired. Concurrent lookups share one fetch. It fails
+// only when ctx ends during the fetch; nothing is cached then, since the
+// caller gave up rather than the host.
+func (c *robotsCache) get(ctx context.Context, u *url.URL, userAgent string) (*RobotsTxt, error) {
+	key := u.Scheme + "://" + u.Host
+
+	for {
+		c.mutex.Lock()
+		if entry, ok := c.entries[key]; ok && time.Now().Before(entry.expiresAt) {
+			c.mutex.Unlock()
+			return entry.robots, nil
+		}
+		if fetch, ok := c.fetching[key]; ok {
+			c.mutex.Unlock()
+			select {
+			case <-fetch.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if fetch.err == nil {
+				return fetch.robots, nil
+			}
+			// The caller that fetched gave up, try again
+			continue
+		}
+		fetch := &robotsFetch{done: make(chan struct{})}
+		c.fetching[key] = fetch
+		c.mutex.Unlock()
+
+		robots, ttl := c.fetch(ctx, key, userAgent)
+
+		c.mutex.Lock()
+		delete(c.fetching, key)
+		if err := ctx.Err(); err != nil {
+			fetch.err = err
+		} else {
+			fetch.robots = robots
+			c.entries[key] = &robotsEntry{robots: robots, expiresAt: time.Now().Add(ttl)}
+		}
+		c.mutex.Unlock()
+		close(fetch.done)
+
+		return fetch.robots, fetch.err
+	}
+}
+
+func (c *robotsCache) fetch(ctx context.Context, origin, userAgent string) (*RobotsTxt, time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, robotsFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", origin+"/robots.txt", nil)
+	if err != nil {
+		return &RobotsTxt{disallowAll: true}, robotsErrorTTL
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return &RobotsTxt{disallowAll: true}, robotsErrorTTL
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		// Read the whole file first so that an interrupted transfer isn't
+		// cached as a shorter set of rules
+		body, err := io.ReadAll(io.LimitReader(resp.Body, robotsMaxSize))
+		if err != nil {
+			return &RobotsTxt{disallowAll: true}, robotsErrorTTL
+		}
+		return ParseRobotsTxt(bytes.NewReader(body)), robotsCacheTTL
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		// No robots.txt means no restrictions
+		return &RobotsTxt{}, robotsCacheTTL
+	default:
+		return &RobotsTxt{disallowAll: true}, robotsErrorTTL
+	}
+}
+
+// checkRobots reports whether the options allow fetching rawURL and passes
+// the host's Crawl-delay on to the fetch scheduler.
+func (s *Service) checkRobots(ctx context.Context, rawURL string, options *CrawlingOptions) (bool, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return false, &ScrapeError{Kind: ErrorKindPolicy, URL: rawURL, Err: errors.New("invalid URL")}
+	}
+
+	userAgent := userAgentFor(options)
+	robots, err := s.robots.get(ctx, u, userAgent)
+	if err != nil {
+		return false, &ScrapeError{Kind: classifyRequestError(err), URL: rawURL, Err: err}
+	}
+
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	if !robots.Allowed(userAgent, path) {
+		return false, nil
+	}
+
+	s.fetches.setCrawlDelay(u.Host, robots.CrawlDelay(userAgent))
+
+	return true, nil
+}
@@ -0,0 +1,174 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testRobotsTxt = `
+# Comment line
+User-agent: *
+Disallow: /private/
+Allow: /private/public-*.html$
+Disallow: /*.pdf$
+Crawl-delay: 2
+
+User-agent: SpecialBot
+User-agent: OtherBot
+Disallow: /
+
+Sitemap: https://example.com/sitemap.xml
+`
+
+func TestParseRobotsTxt_Rules(t *testing.T) {
+	robots := ParseRobotsTxt(strings.NewReader(testRobotsTxt))
+
+	tests := []struct {
+		agent   string
+		path    string
+		allowed bool
+	}{
+		{"Mozilla/5.0", "/", true},
+		{"Mozilla/5.0", "/private/secret.html", false},
+		{"Mozilla/5.0", "/private/public-page.html", true},
+		{"Mozilla/5.0", "/private/public-page.html?x=1", false},
+		{"Mozilla/5.0", "/docs/file.pdf", false},
+		{"Mozilla/5.0", "/docs/file.pdf?download=1", true},
+		{"SpecialBot/1.0", "/anything", false},
+		{"otherbot", "/", false},
+		{"SpecialBot/1.0", "/robots.txt", true},
+	}
+
+	for _, tt := range tests {
+		if got := robots.Allowed(tt.agent, tt.path); got != tt.allowed {
+			t.Errorf("Allowed(%q, %q) = %v, want %v", tt.agent, tt.path, got, tt.allowed)
+		}
+	}
+
+	if delay := robots.CrawlDelay("Mozilla/5.0"); delay != 2*time.Second {
+		t.Errorf("Crawl delay should be 2s, got %v", delay)
+	}
+
+	if delay := robots.CrawlDelay("SpecialBot"); delay != 0 {
+		t.Errorf("SpecialBot should have no crawl delay, got %v", delay)
+	}
+
+	if len(robots.Sitemaps) != 1 || robots.Sitemaps[0] != "https://example.com/sitemap.xml" {
+		t.Errorf("Should parse the sitemap line, got %v", robots.Sitemaps)
+	}
+}
+
+func TestScrapeWebsite_BlockedByRobots(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "User-agent: *\nDisallow: /blocked\n")
+			return
+		}
+		fmt.Fprint(w, "<html><head><title>ok</title></head></html>")
+	}))
+	defer server.Close()
+
+	service := newTestService()
+	options := &CrawlingOptions{RespectRobotsTxt: true}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	data, err := service.ScrapeWebsiteWithOptions(ctx, server.URL+"/blocked/page", options)
+	if err != nil {
+		t.Fatalf("A blocked URL should not be an error: %v", err)
+	}
+	if data.Status != ScrapeStatusBlockedByRobots {
+		t.Errorf("Status should be '%s', got '%s'", ScrapeStatusBlockedByRobots, data.Status)
+	}
+
+	data, err = service.ScrapeWebsiteWithOptions(ctx, server.URL+"/open", options)
+	if err != nil {
+		t.Fatalf("Should not return an error: %v", err)
+	}
+	if data.Status != ScrapeStatusOK || data.Title != "ok" {
+		t.Errorf("Allowed URL should be scraped, got status '%s' and title '%s'", data.Status, data.Title)
+	}
+}
+
+func TestRobotsTxt_ProductTokens(t *testing.T) {
+	robots := ParseRobotsTxt(strings.NewReader("User-agent: bot\nDisallow: /\n\nUser-agent: *\nDisallow: /private/\n"))
+
+	tests := []struct {
+		agent   string
+		allowed bool
+	}{
+		{"bot/1.0", false},
+		{"Mozilla/5.0 (compatible; bot/2.1)", false},
+		{"Googlebot/2.1", true},
+		{"Mozilla/5.0 (compatible; RobotScanner)", true},
+	}
+
+	for _, tt := range tests {
+		if got := robots.Allowed(tt.agent, "/page"); got != tt.allowed {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.agent, got, tt.allowed)
+		}
+	}
+}
+
+func TestRobotsCache_CancelledFetchNotCached(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+	}))
+	defer server.Close()
+
+	cache := newRobotsCache(server.Client())
+	u, _ := url.Parse(server.URL + "/page")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cache.get(ctx, u, "TestBot"); err == nil {
+		t.Fatal("Should fail when the caller's context is cancelled")
+	}
+
+	robots, err := cache.get(context.Background(), u, "TestBot")
+	if err != nil {
+		t.Fatalf("Should not return an error: %v", err)
+	}
+	if !robots.Allowed("TestBot", "/page") {
+		t.Error("Should not have cached the cancelled fetch as a full disallow")
+	}
+}
+
+func TestRobotsCache_ConcurrentLookupsShareFetch(t *testing.T) {
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+	}))
+	defer server.Close()
+
+	cache := newRobotsCache(server.Client())
+	u, _ := url.Parse(server.URL + "/page")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			robots, err := cache.get(context.Background(), u, "TestBot")
+			if err != nil || robots.Allowed("TestBot", "/private/page") {
+				t.Errorf("Should get the fetched rules, got error %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("Expected robots.txt to be fetched once, got %d fetches", n)
+	}
+}
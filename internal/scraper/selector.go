@@ -0,0 +1,259 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PaesslerAG/gval"
+	"github.com/PaesslerAG/jsonpath"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+)
+
+// SelectorKind is the language of a selector, given as a prefix like
+// "xpath://h1". Selectors without a prefix are CSS.
+type SelectorKind string
+
+const (
+	SelectorCSS      SelectorKind = "css"
+	SelectorXPath    SelectorKind = "xpath"
+	SelectorRegex    SelectorKind = "regex"
+	SelectorJSONPath SelectorKind = "jsonpath"
+)
+
+// selector is a compiled CSS, XPath, regex or JSONPath selector.
+type selector struct {
+	kind     SelectorKind
+	expr     string
+	css      cascadia.Selector
+	xpath    *xpath.Expr
+	regex    *regexp.Regexp
+	jsonpath gval.Evaluable
+}
+
+// Selectors are evaluated against a scope, which is one of:
+//   - *goquery.Selection for HTML documents
+//   - *xmlquery.Node for XML documents and feeds
+//   - jsonValue for JSON documents
+//   - textValue for plain text and PDF documents
+
+// jsonValue is a decoded JSON value used as a selector scope.
+type jsonValue struct {
+	value interface{}
+}
+
+// textValue is plain text used as a selector scope.
+type textValue string
+
+// selectorMatch is a single selector result: a node that nested rules can be
+// evaluated against, or a plain value for regex matches and XPath expressions
+// returning strings, numbers or booleans.
+type selectorMatch struct {
+	node  interface{}
+	value string
+}
+
+// text returns the trimmed text of the match.
+func (m selectorMatch) text() string {
+	switch node := m.node.(type) {
+	case *goquery.Selection:
+		return strings.TrimSpace(node.Text())
+	case *xmlquery.Node:
+		return strings.TrimSpace(node.InnerText())
+	case jsonValue:
+		return jsonText(node.value)
+	}
+	return strings.TrimSpace(m.value)
+}
+
+// parseSelector splits a selector into its kind and expression.
+func parseSelector(raw string) (SelectorKind, string) {
+	for _, kind := range []SelectorKind{SelectorCSS, SelectorXPath, SelectorRegex, SelectorJSONPath} {
+		if expr, ok := strings.CutPrefix(raw, string(kind)+":"); ok {
+			return kind, strings.TrimSpace(expr)
+		}
+	}
+	return SelectorCSS, strings.TrimSpace(raw)
+}
+
+// compileSelector parses and compiles a prefixed selector.
+func compileSelector(raw string) (*selector, error) {
+	kind, expr := parseSelector(raw)
+	if expr == "" {
+		return nil, fmt.Errorf("empty %s selector", kind)
+	}
+
+	sel := &selector{kind: kind, expr: expr}
+	var err error
+	switch kind {
+	case SelectorXPath:
+		sel.xpath, err = xpath.Compile(expr)
+	case SelectorRegex:
+		sel.regex, err = regexp.Compile(expr)
+	case SelectorJSONPath:
+		sel.jsonpath, err = jsonpath.New(expr)
+	default:
+		sel.css, err = cascadia.Compile(expr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s selector %q: %w", kind, expr, err)
+	}
+	return sel, nil
+}
+
+// matches evaluates the selector relative to scope. Regex selectors run
+// against the source of the scope and yield capture group 1 if present,
+// otherwise the whole match.
+func (sel *selector) matches(scope interface{}) ([]selectorMatch, error) {
+	if sel.kind == SelectorRegex {
+		return sel.regexMatches(scopeSource(scope)), nil
+	}
+
+	switch root := scope.(type) {
+	case *goquery.Selection:
+		return sel.htmlMatches(root)
+	case *xmlquery.Node:
+		if sel.kind == SelectorXPath {
+			return sel.xmlMatches(root), nil
+		}
+	case jsonValue:
+		if sel.kind == SelectorJSONPath {
+			return sel.jsonMatches(root)
+		}
+	}
+	return nil, fmt.Errorf("%s selectors are not supported for this content type", sel.kind)
+}
+
+func (sel *selector) htmlMatches(root *goquery.Selection) ([]selectorMatch, error) {
+	var matches []selectorMatch
+
+	switch sel.kind {
+	case SelectorCSS:
+		root.FindMatcher(sel.css).Each(func(i int, s *goquery.Selection) {
+			matches = append(matches, selectorMatch{node: s})
+		})
+	case SelectorXPath:
+		for _, node := range root.Nodes {
+			v := sel.xpath.Evaluate(htmlquery.CreateXPathNavigator(node))
+			if _, ok := v.(*xpath.NodeIterator); !ok {
+				matches = append(matches, selectorMatch{value: xpathScalar(v)})
+				continue
+			}
+			for _, n := range htmlquery.QuerySelectorAll(node, sel.xpath) {
+				matches = append(matches, selectorMatch{node: nodeSelection(n)})
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%s selectors are not supported for HTML", sel.kind)
+	}
+
+	return matches, nil
+}
+
+func (sel *selector) xmlMatches(root *xmlquery.Node) []selectorMatch {
+	v := sel.xpath.Evaluate(xmlquery.CreateXPathNavigator(root))
+	if _, ok := v.(*xpath.NodeIterator); !ok {
+		return []selectorMatch{{value: xpathScalar(v)}}
+	}
+
+	var matches []selectorMatch
+	for _, n := range xmlquery.QuerySelectorAll(root, sel.xpath) {
+		matches = append(matches, selectorMatch{node: n})
+	}
+	return matches
+}
+
+// jsonMatches evaluates a JSONPath expression. Paths with wildcards, recursive
+// descent, filters, slices or unions yield one match per result; other paths
+// yield the single value they point to.
+func (sel *selector) jsonMatches(root jsonValue) ([]selectorMatch, error) {
+	result, err := sel.jsonpath(context.Background(), root.value)
+	if err != nil {
+		// Paths pointing to missing keys or indexes simply do not match
+		return nil, nil
+	}
+
+	list, ok := result.([]interface{})
+	if !ok || !strings.ContainsAny(sel.expr, "*?:,") && !strings.Contains(sel.expr, "..") {
+		return []selectorMatch{{node: jsonValue{result}}}, nil
+	}
+
+	matches := make([]selectorMatch, 0, len(list))
+	for _, v := range list {
+		matches = append(matches, selectorMatch{node: jsonValue{v}})
+	}
+	return matches, nil
+}
+
+func (sel *selector) regexMatches(source string) []selectorMatch {
+	var matches []selectorMatch
+	for _, m := range sel.regex.FindAllStringSubmatch(source, -1) {
+		value := m[0]
+		if len(m) > 1 {
+			value = m[1]
+		}
+		matches = append(matches, selectorMatch{value: value})
+	}
+	return matches
+}
+
+// scopeSource returns the source text of a scope for regex selectors.
+func scopeSource(scope interface{}) string {
+	switch root := scope.(type) {
+	case *goquery.Selection:
+		var source strings.Builder
+		for _, node := range root.Nodes {
+			html.Render(&source, node)
+		}
+		return source.String()
+	case *xmlquery.Node:
+		return root.OutputXML(true)
+	case jsonValue:
+		if s, ok := root.value.(string); ok {
+			return s
+		}
+		b, _ := json.Marshal(root.value)
+		return string(b)
+	case textValue:
+		return string(root)
+	}
+	return ""
+}
+
+// xpathScalar formats the result of an XPath expression that does not
+// return nodes, such as count() or string().
+func xpathScalar(v interface{}) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+// jsonText returns a JSON value as text: strings as they are, everything
+// else in its JSON encoding.
+func jsonText(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strings.TrimSpace(s)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// nodeSelection wraps a node returned by XPath in a selection. Attribute
+// results are synthetic nodes whose text is the attribute value.
+func nodeSelection(n *html.Node) *goquery.Selection {
+	return goquery.NewDocumentFromNode(n).Selection
+}
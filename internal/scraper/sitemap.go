@@ -0,0 +1,279 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// sitemapMaxSize is the maximum uncompressed size allowed by the protocol
+	sitemapMaxSize = 50 * 1024 * 1024
+	// sitemapMaxURLs caps the number of URLs collected from all sitemaps
+	sitemapMaxURLs = 100000
+	// sitemapMaxDepth limits how deep sitemap indexes are followed
+	sitemapMaxDepth = 3
+)
+
+type SeedMode string
+
+const (
+	SeedModeURL     SeedMode = "url"
+	SeedModeSitemap SeedMode = "sitemap"
+)
+
+type SitemapURL struct {
+	Loc        string     `json:"loc"`
+	LastMod    *time.Time `json:"lastmod,omitempty"`
+	ChangeFreq string     `json:"changefreq,omitempty"`
+	Priority   *float64   `json:"priority,omitempty"`
+	Sitemap    string     `json:"sitemap"`
+}
+
+type SitemapResult struct {
+	Sitemaps []string     `json:"sitemaps"`
+	URLs     []SitemapURL `json:"urls"`
+	Count    int          `json:"count"`
+	Errors   []string     `json:"errors,omitempty"`
+}
+
+// sitemapDocument covers both <urlset> and <sitemapindex> documents.
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapEntry `xml:"url"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod"`
+	ChangeFreq string `xml:"changefreq"`
+	Priority   string `xml:"priority"`
+}
+
+// Sitemap collects the URLs listed in the sitemaps of siteURL. Sitemaps are
+// taken from options.SitemapURL if set, otherwise discovered through
+// robots.txt with /sitemap.xml as fallback. The URLs are filtered by
+// options.SitemapModifiedSince and the include/exclude/domain filters.
+func (s *Service) Sitemap(ctx context.Context, siteURL string, options *CrawlingOptions) (*SitemapResult, error) {
+	if err := s.checkURL(siteURL); err != nil {
+		return nil, err
+	}
+	site, _ := url.Parse(siteURL)
+
+	result := &SitemapResult{
+		Sitemaps: s.discoverSitemaps(ctx, site, options),
+		URLs:     make([]SitemapURL, 0),
+	}
+
+	seen := make(map[string]bool)
+	for _, sitemapURL := range result.Sitemaps {
+		s.collectSitemap(ctx, sitemapURL, options, 0, seen, result)
+	}
+
+	result.URLs = s.filterSitemapURLs(result.URLs, options)
+	result.Count = len(result.URLs)
+
+	s.logger.Infof("Sitemap collected for %s: %d URLs from %d sitemaps", siteURL, result.Count, len(result.Sitemaps))
+
+	return result, nil
+}
+
+// discoverSitemaps returns the sitemap locations for a site.
+func (s *Service) discoverSitemaps(ctx context.Context, site *url.URL, options *CrawlingOptions) []string {
+	if options.SitemapURL != "" {
+		return []string{options.SitemapURL}
+	}
+
+	robots, err := s.robots.get(ctx, site, userAgentFor(options))
+	if err == nil && len(robots.Sitemaps) > 0 {
+		return robots.Sitemaps
+	}
+
+	return []string{site.Scheme + "://" + site.Host + "/sitemap.xml"}
+}
+
+// collectSitemap fetches a sitemap and adds its URLs to result, following
+// sitemap indexes up to sitemapMaxDepth.
+func (s *Service) collectSitemap(ctx context.Context, sitemapURL string, options *CrawlingOptions, depth int, seen map[string]bool, result *SitemapResult) {
+	if seen[sitemapURL] || len(result.URLs) >= sitemapMaxURLs {
+		return
+	}
+	seen[sitemapURL] = true
+
+	urls, children, err := s.fetchSitemap(ctx, sitemapURL, options)
+	if err != nil {
+		s.logger.Errorf("Error fetching sitemap %s: %v", sitemapURL, err)
+		result.Errors = append(result.Errors, err.Error())
+		return
+	}
+
+	for _, u := range urls {
+		if len(result.URLs) >= sitemapMaxURLs {
+			break
+		}
+		result.URLs = append(result.URLs, u)
+	}
+
+	if depth >= sitemapMaxDepth {
+		return
+	}
+	for _, child := range children {
+		s.collectSitemap(ctx, child, options, depth+1, seen, result)
+	}
+}
+
+// fetchSitemap downloads and parses a single sitemap. It returns the page
+// URLs of a urlset or text sitemap and the child sitemaps of an index.
+func (s *Service) fetchSitemap(ctx context.Context, sitemapURL string, options *CrawlingOptions) ([]SitemapURL, []string, error) {
+	fetched, err := s.fetch(ctx, sitemapURL, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer fetched.release(fetched.resp)
+	defer fetched.resp.Body.Close()
+
+	if fetched.resp.StatusCode != 200 {
+		return nil, nil, &ScrapeError{Kind: ErrorKindHTTPStatus, URL: sitemapURL, StatusCode: fetched.resp.StatusCode}
+	}
+
+	urls, children, err := ParseSitemap(fetched.resp.Body, sitemapURL)
+	if err != nil {
+		return nil, nil, &ScrapeError{Kind: ErrorKindParse, URL: sitemapURL, Err: err}
+	}
+	return urls, children, nil
+}
+
+// ParseSitemap parses an XML urlset, an XML sitemap index or a text sitemap,
+// each optionally gzip-compressed. For an index the child sitemap locations
+// are returned instead of URLs.
+func ParseSitemap(r io.Reader, sitemapURL string) ([]SitemapURL, []string, error) {
+	reader := bufio.NewReader(r)
+
+	// Detect gzip by its magic bytes rather than trusting headers
+	if magic, err := reader.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid gzip sitemap: %w", err)
+		}
+		defer gz.Close()
+		reader = bufio.NewReader(gz)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(reader, sitemapMaxSize))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read sitemap: %w", err)
+	}
+
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")))
+	if len(trimmed) > 0 && trimmed[0] != '<' {
+		return parseTextSitemap(trimmed, sitemapURL), nil, nil
+	}
+
+	var doc sitemapDocument
+	if err := xml.Unmarshal(trimmed, &doc); err != nil {
+		return nil, nil, fmt.Errorf("invalid sitemap XML: %w", err)
+	}
+
+	switch doc.XMLName.Local {
+	case "sitemapindex":
+		children := make([]string, 0, len(doc.Sitemaps))
+		for _, entry := range doc.Sitemaps {
+			if loc := strings.TrimSpace(entry.Loc); loc != "" {
+				children = append(children, loc)
+			}
+		}
+		return nil, children, nil
+	case "urlset":
+		urls := make([]SitemapURL, 0, len(doc.URLs))
+		for _, entry := range doc.URLs {
+			loc := strings.TrimSpace(entry.Loc)
+			if loc == "" {
+				continue
+			}
+			u := SitemapURL{
+				Loc:        loc,
+				LastMod:    parseW3CDatetime(entry.LastMod),
+				ChangeFreq: strings.ToLower(strings.TrimSpace(entry.ChangeFreq)),
+				Sitemap:    sitemapURL,
+			}
+			if priority, err := strconv.ParseFloat(strings.TrimSpace(entry.Priority), 64); err == nil {
+				u.Priority = &priority
+			}
+			urls = append(urls, u)
+		}
+		return urls, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unexpected sitemap root element <%s>", doc.XMLName.Local)
+	}
+}
+
+// parseTextSitemap reads a text sitemap with one URL per line.
+func parseTextSitemap(content []byte, sitemapURL string) []SitemapURL {
+	var urls []SitemapURL
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "http://") || strings.HasPrefix(line, "https://") {
+			urls = append(urls, SitemapURL{Loc: line, Sitemap: sitemapURL})
+		}
+	}
+	return urls
+}
+
+// parseW3CDatetime parses the date formats allowed for <lastmod>.
+func parseW3CDatetime(value string) *time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	layouts := []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04Z07:00",
+		"2006-01-02T15:04:05",
+		"2006-01-02",
+		"2006-01",
+		"2006",
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// filterSitemapURLs applies the modification date and URL filters of the
+// options. URLs without <lastmod> are dropped when a date filter is set.
+func (s *Service) filterSitemapURLs(urls []SitemapURL, options *CrawlingOptions) []SitemapURL {
+	locs := make([]string, 0, len(urls))
+	for _, u := range urls {
+		locs = append(locs, u.Loc)
+	}
+	allowed := make(map[string]bool)
+	for _, loc := range s.filterUrls(locs, options) {
+		allowed[loc] = true
+	}
+
+	filtered := make([]SitemapURL, 0, len(urls))
+	seen := make(map[string]bool)
+	for _, u := range urls {
+		if !allowed[u.Loc] || seen[u.Loc] {
+			continue
+		}
+		if since := options.SitemapModifiedSince; since != nil && (u.LastMod == nil || u.LastMod.Before(*since)) {
+			continue
+		}
+		seen[u.Loc] = true
+		filtered = append(filtered, u)
+	}
+	return filtered
+}
@@ -0,0 +1,137 @@
+package scraper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSitemapIndex = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>%[1]s/sitemap-pages.xml.gz</loc></sitemap>
+  <sitemap><loc>%[1]s/sitemap-extra.txt</loc></sitemap>
+</sitemapindex>`
+
+const testSitemapPages = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>%[1]s/new</loc><lastmod>2026-05-01</lastmod><changefreq>daily</changefreq><priority>0.8</priority></url>
+  <url><loc>%[1]s/old</loc><lastmod>2020-01-01T10:00:00+00:00</lastmod></url>
+  <url><loc>%[1]s/private/new</loc><lastmod>2026-06-01</lastmod></url>
+</urlset>`
+
+func newSitemapTestServer() *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nAllow: /\nSitemap: %s/sitemap-index.xml\n", server.URL)
+		case "/sitemap-index.xml":
+			fmt.Fprintf(w, testSitemapIndex, server.URL)
+		case "/sitemap-pages.xml.gz":
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			fmt.Fprintf(gz, testSitemapPages, server.URL)
+			gz.Close()
+			w.Header().Set("Content-Type", "application/x-gzip")
+			w.Write(buf.Bytes())
+		case "/sitemap-extra.txt":
+			fmt.Fprintf(w, "%s/text-page\n\nnot-a-url\n", server.URL)
+		default:
+			fmt.Fprintf(w, "<html><head><title>%s</title></head></html>", r.URL.Path)
+		}
+	}))
+	return server
+}
+
+func TestParseSitemap_URLSet(t *testing.T) {
+	urls, children, err := ParseSitemap(strings.NewReader(fmt.Sprintf(testSitemapPages, "https://example.com")), "https://example.com/sitemap.xml")
+	if err != nil {
+		t.Fatalf("Should parse the urlset: %v", err)
+	}
+	if len(children) != 0 {
+		t.Errorf("A urlset has no child sitemaps, got %v", children)
+	}
+	if len(urls) != 3 {
+		t.Fatalf("Should parse 3 URLs, got %d", len(urls))
+	}
+
+	first := urls[0]
+	if first.LastMod == nil || first.LastMod.Format("2006-01-02") != "2026-05-01" {
+		t.Errorf("Unexpected lastmod: %v", first.LastMod)
+	}
+	if first.ChangeFreq != "daily" || first.Priority == nil || *first.Priority != 0.8 {
+		t.Errorf("Unexpected changefreq/priority: %s/%v", first.ChangeFreq, first.Priority)
+	}
+}
+
+func TestSitemap_DiscoversAndFilters(t *testing.T) {
+	server := newSitemapTestServer()
+	defer server.Close()
+
+	service := newTestService()
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	options := &CrawlingOptions{
+		SitemapModifiedSince: &since,
+		ExcludePatterns:      []string{"/private/"},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := service.Sitemap(ctx, server.URL, options)
+	if err != nil {
+		t.Fatalf("Should not return an error: %v", err)
+	}
+
+	if len(result.Sitemaps) != 1 || result.Sitemaps[0] != server.URL+"/sitemap-index.xml" {
+		t.Errorf("Should discover the sitemap from robots.txt, got %v", result.Sitemaps)
+	}
+	if result.Count != 1 || result.URLs[0].Loc != server.URL+"/new" {
+		t.Errorf("Only /new should pass the filters, got %+v", result.URLs)
+	}
+
+	// Without a date filter the text sitemap entry is included as well
+	result, err = service.Sitemap(ctx, server.URL, &CrawlingOptions{})
+	if err != nil {
+		t.Fatalf("Should not return an error: %v", err)
+	}
+	if result.Count != 4 {
+		t.Errorf("Should collect 4 URLs, got %d", result.Count)
+	}
+}
+
+func TestCrawl_SitemapSeedMode(t *testing.T) {
+	server := newSitemapTestServer()
+	defer server.Close()
+
+	service := newTestService()
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	options := &CrawlingOptions{
+		SeedMode:             SeedModeSitemap,
+		SitemapModifiedSince: &since,
+		MaxPages:             10,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := service.Crawl(ctx, server.URL, options)
+	if err != nil {
+		t.Fatalf("Should not return an error: %v", err)
+	}
+
+	if result.PageCount != 2 {
+		t.Fatalf("Should crawl the 2 recently modified URLs, got %d", result.PageCount)
+	}
+	for _, page := range result.Pages {
+		if page.Depth != 0 || page.ParentURL != server.URL+"/sitemap-pages.xml.gz" {
+			t.Errorf("Sitemap seeds should have depth 0 and the sitemap as parent, got %+v", page)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package scraper
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// StructuredData holds the machine-readable metadata embedded in a page.
+// Microdata and RDFa items use the same shape as JSON-LD objects: "@type",
+// "@context" and "@id" keys plus one key per property, where repeated
+// properties become arrays and nested items become objects.
+type StructuredData struct {
+	JSONLD    []interface{}            `json:"json_ld,omitempty"`
+	Microdata []map[string]interface{} `json:"microdata,omitempty"`
+	RDFa      []map[string]interface{} `json:"rdfa,omitempty"`
+	OpenGraph map[string][]string      `json:"open_graph,omitempty"`
+	Errors    []string                 `json:"errors,omitempty"`
+}
+
+// openGraphPrefixes are the meta property prefixes collected as OpenGraph.
+var openGraphPrefixes = []string{"og:", "article:", "book:", "profile:", "music:", "video:", "product:", "fb:", "twitter:"}
+
+// extractStructuredData parses JSON-LD, Microdata, RDFa and OpenGraph data.
+func extractStructuredData(doc *goquery.Document, base *url.URL) *StructuredData {
+	data := &StructuredData{
+		OpenGraph: make(map[string][]string),
+	}
+
+	// JSON-LD blocks
+	doc.Find(`script[type="application/ld+json"]`).Each(func(i int, s *goquery.Selection) {
+		content := strings.TrimSpace(s.Text())
+		content = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(content, "<!--"), "-->"))
+		if content == "" {
+			return
+		}
+
+		var value interface{}
+		if err := json.Unmarshal([]byte(content), &value); err != nil {
+			data.Errors = append(data.Errors, "invalid JSON-LD block: "+err.Error())
+			return
+		}
+		if list, ok := value.([]interface{}); ok {
+			data.JSONLD = append(data.JSONLD, list...)
+		} else {
+			data.JSONLD = append(data.JSONLD, value)
+		}
+	})
+
+	// Microdata: top-level items are scopes that are not a property themselves
+	doc.Find("[itemscope]:not([itemprop])").Each(func(i int, s *goquery.Selection) {
+		data.Microdata = append(data.Microdata, microdataItem(s, base))
+	})
+
+	// RDFa: top-level resources are typed elements that are not a property
+	doc.Find("[typeof]:not([property])").Each(func(i int, s *goquery.Selection) {
+		data.RDFa = append(data.RDFa, rdfaItem(s, base, inheritedVocab(s)))
+	})
+
+	// OpenGraph and related meta properties, keeping repeated values
+	doc.Find("meta[property], meta[name]").Each(func(i int, s *goquery.Selection) {
+		key, ok := s.Attr("property")
+		if !ok {
+			key, _ = s.Attr("name")
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		content, ok := s.Attr("content")
+		if !ok {
+			return
+		}
+		for _, prefix := range openGraphPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				data.OpenGraph[key] = append(data.OpenGraph[key], content)
+				break
+			}
+		}
+	})
+
+	return data
+}
+
+// microdataItem converts an itemscope element into a JSON-LD style object.
+func microdataItem(s *goquery.Selection, base *url.URL) map[string]interface{} {
+	item := make(map[string]interface{})
+
+	if itemType, ok := s.Attr("itemtype"); ok {
+		setVocabType(item, strings.Fields(itemType))
+	}
+	if id, ok := s.Attr("itemid"); ok {
+		item["@id"] = resolveOrRaw(base, id)
+	}
+
+	collectMicrodataProperties(s, item, base)
+	return item
+}
+
+// collectMicrodataProperties walks the descendants of an item, stopping at
+// nested item scopes which belong to another item.
+func collectMicrodataProperties(s *goquery.Selection, item map[string]interface{}, base *url.URL) {
+	s.Children().Each(func(i int, child *goquery.Selection) {
+		_, isScope := child.Attr("itemscope")
+		props, isProp := child.Attr("itemprop")
+
+		if isProp {
+			var value interface{}
+			if isScope {
+				value = microdataItem(child, base)
+			} else {
+				value = propertyValue(child, base, "")
+			}
+			for _, name := range strings.Fields(props) {
+				addProperty(item, name, value)
+			}
+		}
+
+		if !isScope {
+			collectMicrodataProperties(child, item, base)
+		}
+	})
+}
+
+// rdfaItem converts a typeof element into a JSON-LD style object.
+func rdfaItem(s *goquery.Selection, base *url.URL, vocab string) map[string]interface{} {
+	if v, ok := s.Attr("vocab"); ok {
+		vocab = v
+	}
+
+	item := make(map[string]interface{})
+	if vocab != "" {
+		item["@context"] = vocab
+	}
+	if typeOf, ok := s.Attr("typeof"); ok {
+		item["@type"] = singleOrList(strings.Fields(typeOf))
+	}
+	for _, attr := range []string{"resource", "about", "href"} {
+		if id, ok := s.Attr(attr); ok {
+			item["@id"] = resolveOrRaw(base, id)
+			break
+		}
+	}
+
+	collectRDFaProperties(s, item, base, vocab)
+	return item
+}
+
+func collectRDFaProperties(s *goquery.Selection, item map[string]interface{}, base *url.URL, vocab string) {
+	s.Children().Each(func(i int, child *goquery.Selection) {
+		childVocab := vocab
+		if v, ok := child.Attr("vocab"); ok {
+			childVocab = v
+		}
+		_, isTyped := child.Attr("typeof")
+		props, isProp := child.Attr("property")
+
+		if isProp {
+			var value interface{}
+			if isTyped {
+				value = rdfaItem(child, base, childVocab)
+			} else {
+				value = propertyValue(child, base, "resource")
+			}
+			for _, name := range strings.Fields(props) {
+				addProperty(item, name, value)
+			}
+		}
+
+		if !isTyped {
+			collectRDFaProperties(child, item, base, childVocab)
+		}
+	})
+}
+
+// inheritedVocab returns the closest vocab attribute of the ancestors of s.
+func inheritedVocab(s *goquery.Selection) string {
+	vocab, _ := s.ParentsFiltered("[vocab]").First().Attr("vocab")
+	return vocab
+}
+
+// propertyValue returns the value of a property element following the
+// Microdata rules; extraAttr is an additional attribute checked first.
+func propertyValue(s *goquery.Selection, base *url.URL, extraAttr string) interface{} {
+	if extraAttr != "" {
+		if v, ok := s.Attr(extraAttr); ok {
+			return resolveOrRaw(base, v)
+		}
+	}
+	if v, ok := s.Attr("content"); ok {
+		return v
+	}
+
+	switch goquery.NodeName(s) {
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		if v, ok := s.Attr("src"); ok {
+			return resolveOrRaw(base, v)
+		}
+	case "a", "area", "link":
+		if v, ok := s.Attr("href"); ok {
+			return resolveOrRaw(base, v)
+		}
+	case "object":
+		if v, ok := s.Attr("data"); ok {
+			return resolveOrRaw(base, v)
+		}
+	case "data", "meter":
+		if v, ok := s.Attr("value"); ok {
+			return v
+		}
+	case "time":
+		if v, ok := s.Attr("datetime"); ok {
+			return v
+		}
+	}
+
+	return strings.Join(strings.Fields(s.Text()), " ")
+}
+
+// addProperty sets a property, turning it into a list when it repeats.
+func addProperty(item map[string]interface{}, name string, value interface{}) {
+	existing, ok := item[name]
+	if !ok {
+		item[name] = value
+		return
+	}
+	if list, ok := existing.([]interface{}); ok {
+		item[name] = append(list, value)
+		return
+	}
+	item[name] = []interface{}{existing, value}
+}
+
+// setVocabType splits Microdata item types like "https://schema.org/Product"
+// into "@context" and "@type".
+func setVocabType(item map[string]interface{}, types []string) {
+	if len(types) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(types))
+	for _, t := range types {
+		i := strings.LastIndexAny(t, "/#")
+		if i < 0 || i == len(t)-1 {
+			names = append(names, t)
+			continue
+		}
+		if _, ok := item["@context"]; !ok {
+			item["@context"] = t[:i+1]
+		}
+		names = append(names, t[i+1:])
+	}
+	item["@type"] = singleOrList(names)
+}
+
+func singleOrList(values []string) interface{} {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return values
+}
+
+// resolveOrRaw resolves a URL attribute, falling back to the raw value.
+func resolveOrRaw(base *url.URL, raw string) string {
+	if u := resolveURL(base, raw); u != nil {
+		return u.String()
+	}
+	return strings.TrimSpace(raw)
+}
@@ -0,0 +1,96 @@
+package scraper
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const testStructuredHTML = `<html><head>
+<meta property="og:title" content="Widget">
+<meta property="og:image" content="https://example.com/a.jpg">
+<meta property="og:image" content="https://example.com/b.jpg">
+<meta name="twitter:card" content="summary">
+<script type="application/ld+json">{"@context": "https://schema.org", "@type": "Article", "headline": "Hello"}</script>
+<script type="application/ld+json">[{"@type": "Organization", "name": "ACME"}, {"@type": "WebSite"}]</script>
+<script type="application/ld+json">{not json}</script>
+</head><body>
+<div itemscope itemtype="https://schema.org/Product">
+  <span itemprop="name">Widget</span>
+  <img itemprop="image" src="/img/widget.png">
+  <div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+    <meta itemprop="priceCurrency" content="EUR">
+    <span itemprop="price" content="9.99">9,99 €</span>
+  </div>
+  <span itemprop="color">red</span>
+  <span itemprop="color">blue</span>
+</div>
+<div vocab="https://schema.org/" typeof="Person">
+  <span property="name">Jane Doe</span>
+  <a property="url" href="/jane">Profile</a>
+  <div property="address" typeof="PostalAddress">
+    <span property="addressLocality">Berlin</span>
+  </div>
+</div>
+</body></html>`
+
+func TestExtractStructuredData(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(testStructuredHTML))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	base, _ := url.Parse("https://example.com/products/widget")
+
+	data := extractStructuredData(doc, base)
+
+	if len(data.JSONLD) != 3 {
+		t.Fatalf("Should parse 3 JSON-LD objects, got %d", len(data.JSONLD))
+	}
+	if article, ok := data.JSONLD[0].(map[string]interface{}); !ok || article["headline"] != "Hello" {
+		t.Errorf("Unexpected JSON-LD object: %v", data.JSONLD[0])
+	}
+	if len(data.Errors) != 1 {
+		t.Errorf("Invalid JSON-LD should be reported, got %v", data.Errors)
+	}
+
+	if images := data.OpenGraph["og:image"]; len(images) != 2 {
+		t.Errorf("Repeated og:image should be kept, got %v", images)
+	}
+	if card := data.OpenGraph["twitter:card"]; len(card) != 1 || card[0] != "summary" {
+		t.Errorf("Unexpected twitter:card: %v", card)
+	}
+
+	if len(data.Microdata) != 1 {
+		t.Fatalf("Should find 1 top-level microdata item, got %d", len(data.Microdata))
+	}
+	product := data.Microdata[0]
+	if product["@type"] != "Product" || product["@context"] != "https://schema.org/" {
+		t.Errorf("Unexpected product type: %v / %v", product["@type"], product["@context"])
+	}
+	if product["image"] != "https://example.com/img/widget.png" {
+		t.Errorf("Image should be resolved, got %v", product["image"])
+	}
+	if colors, ok := product["color"].([]interface{}); !ok || len(colors) != 2 {
+		t.Errorf("Repeated property should become a list, got %v", product["color"])
+	}
+	offer, ok := product["offers"].(map[string]interface{})
+	if !ok || offer["price"] != "9.99" || offer["priceCurrency"] != "EUR" {
+		t.Errorf("Unexpected nested offer: %v", product["offers"])
+	}
+	if _, leaked := product["price"]; leaked {
+		t.Error("Nested item properties should not leak into the parent")
+	}
+
+	if len(data.RDFa) != 1 {
+		t.Fatalf("Should find 1 top-level RDFa item, got %d", len(data.RDFa))
+	}
+	person := data.RDFa[0]
+	if person["@type"] != "Person" || person["name"] != "Jane Doe" || person["url"] != "https://example.com/jane" {
+		t.Errorf("Unexpected RDFa item: %v", person)
+	}
+	if address, ok := person["address"].(map[string]interface{}); !ok || address["addressLocality"] != "Berlin" {
+		t.Errorf("Unexpected nested RDFa item: %v", person["address"])
+	}
+}
@@ -0,0 +1,153 @@
+package scraper
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// trackingParams are query parameters that only carry analytics information.
+var trackingParams = map[string]bool{
+	"gclid":   true,
+	"dclid":   true,
+	"fbclid":  true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_ga":     true,
+	"_gl":     true,
+}
+
+func isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	return strings.HasPrefix(key, "utm_") || trackingParams[key]
+}
+
+// documentBase returns the URL relative references of a document are resolved
+// against: the <base href> if present, otherwise the response URL.
+func documentBase(doc *goquery.Document, responseURL *url.URL) *url.URL {
+	href, exists := doc.Find("base[href]").First().Attr("href")
+	if !exists {
+		return responseURL
+	}
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return responseURL
+	}
+	return responseURL.ResolveReference(ref)
+}
+
+// resolveURL resolves a raw attribute value against base. It returns nil for
+// empty values, pure fragments and anything that is not an http(s) URL such
+// as javascript:, mailto: or data: references.
+func resolveURL(base *url.URL, raw string) *url.URL {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || strings.HasPrefix(raw, "#") {
+		return nil
+	}
+
+	ref, err := url.Parse(raw)
+	if err != nil {
+		return nil
+	}
+
+	abs := base.ResolveReference(ref)
+	abs.Scheme = strings.ToLower(abs.Scheme)
+	if (abs.Scheme != "http" && abs.Scheme != "https") || abs.Host == "" {
+		return nil
+	}
+	return abs
+}
+
+// NormalizeURL returns the canonical form of u: lowercase scheme and host,
+// no default port, no fragment and "/" for an empty path. Tracking parameters
+// are removed and the query is sorted if the options ask for it.
+func NormalizeURL(u *url.URL, options *CrawlingOptions) string {
+	n := *u
+	n.Scheme = strings.ToLower(n.Scheme)
+	n.Host = strings.ToLower(n.Host)
+	n.Fragment = ""
+	n.RawFragment = ""
+
+	if port := n.Port(); (n.Scheme == "http" && port == "80") || (n.Scheme == "https" && port == "443") {
+		n.Host = strings.TrimSuffix(n.Host, ":"+port)
+	}
+	if n.Path == "" {
+		n.Path = "/"
+		n.RawPath = ""
+	}
+
+	if n.RawQuery != "" && (options.StripTrackingParams || options.SortQueryParams) {
+		n.RawQuery = normalizeQuery(n.RawQuery, options.StripTrackingParams, options.SortQueryParams)
+	}
+	n.ForceQuery = false
+
+	return n.String()
+}
+
+// normalizeQuery filters and sorts the raw query without re-encoding it.
+func normalizeQuery(rawQuery string, stripTracking, sortParams bool) string {
+	pairs := strings.Split(rawQuery, "&")
+
+	kept := pairs[:0]
+	for _, pair := range pairs {
+		if pair == "" {
+			continue
+		}
+		if stripTracking {
+			key, _, _ := strings.Cut(pair, "=")
+			if unescaped, err := url.QueryUnescape(key); err == nil {
+				key = unescaped
+			}
+			if isTrackingParam(key) {
+				continue
+			}
+		}
+		kept = append(kept, pair)
+	}
+
+	if sortParams {
+		sort.Strings(kept)
+	}
+
+	return strings.Join(kept, "&")
+}
+
+// isInternalURL reports whether u points at the same host as page.
+func isInternalURL(u, page *url.URL) bool {
+	return strings.EqualFold(u.Hostname(), page.Hostname())
+}
+
+// extractURLs collects the attribute values of all elements matching
+// selector, resolved against base, normalized and deduplicated.
+func extractURLs(doc *goquery.Document, selector, attr string, base *url.URL, options *CrawlingOptions) []*url.URL {
+	seen := make(map[string]bool)
+	var urls []*url.URL
+
+	doc.Find(selector).Each(func(i int, s *goquery.Selection) {
+		raw, exists := s.Attr(attr)
+		if !exists {
+			return
+		}
+		abs := resolveURL(base, raw)
+		if abs == nil {
+			return
+		}
+
+		normalized := NormalizeURL(abs, options)
+		if seen[normalized] {
+			return
+		}
+		seen[normalized] = true
+
+		if u, err := url.Parse(normalized); err == nil {
+			urls = append(urls, u)
+		}
+	})
+
+	return urls
+}
@@ -0,0 +1,95 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		raw      string
+		options  *CrawlingOptions
+		expected string
+	}{
+		{"HTTP://Example.COM:80/path#top", &CrawlingOptions{}, "http://example.com/path"},
+		{"https://example.com:443", &CrawlingOptions{}, "https://example.com/"},
+		{"https://example.com:8443/a", &CrawlingOptions{}, "https://example.com:8443/a"},
+		{"https://example.com/?b=2&a=1", &CrawlingOptions{}, "https://example.com/?b=2&a=1"},
+		{"https://example.com/?b=2&a=1", &CrawlingOptions{SortQueryParams: true}, "https://example.com/?a=1&b=2"},
+		{"https://example.com/?utm_source=x&id=5&fbclid=y", &CrawlingOptions{StripTrackingParams: true}, "https://example.com/?id=5"},
+		{"https://example.com/?utm_medium=x", &CrawlingOptions{StripTrackingParams: true}, "https://example.com/"},
+	}
+
+	for _, tt := range tests {
+		u, err := url.Parse(tt.raw)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", tt.raw, err)
+		}
+		if got := NormalizeURL(u, tt.options); got != tt.expected {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", tt.raw, got, tt.expected)
+		}
+	}
+}
+
+func TestResolveURL_SkipsNonHTTP(t *testing.T) {
+	base, _ := url.Parse("https://example.com/dir/page.html")
+
+	for _, raw := range []string{"", "#top", "javascript:void(0)", "mailto:a@b.c", "tel:123", "data:image/png;base64,AAAA"} {
+		if u := resolveURL(base, raw); u != nil {
+			t.Errorf("resolveURL(%q) should be skipped, got %s", raw, u)
+		}
+	}
+
+	if u := resolveURL(base, "../img/a.png"); u == nil || u.String() != "https://example.com/img/a.png" {
+		t.Errorf("Relative URL should resolve against the base, got %v", u)
+	}
+}
+
+func TestScrapeWebsite_ResolvesAndClassifiesURLs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head>
+			<base href="/docs/">
+			<link rel="stylesheet" href="https://cdn.example.net/style.css">
+		</head><body>
+			<a href="about">About</a>
+			<a href="/docs/about#team">About again</a>
+			<a href="#top">Top</a>
+			<a href="javascript:void(0)">JS</a>
+			<a href="https://other.example/page">Other</a>
+			<img src="../img/a.png">
+		</body></html>`)
+	}))
+	defer server.Close()
+
+	service := newTestService()
+	options := &CrawlingOptions{ExtractLinks: true, ExtractImages: true, ExtractStyles: true}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	data, err := service.ScrapeWebsiteWithOptions(ctx, server.URL, options)
+	if err != nil {
+		t.Fatalf("Should not return an error: %v", err)
+	}
+
+	if len(data.Links) != 2 {
+		t.Fatalf("Should extract 2 unique links, got %v", data.Links)
+	}
+	if len(data.InternalLinks) != 1 || data.InternalLinks[0] != server.URL+"/docs/about" {
+		t.Errorf("Unexpected internal links: %v", data.InternalLinks)
+	}
+	if len(data.ExternalLinks) != 1 || data.ExternalLinks[0] != "https://other.example/page" {
+		t.Errorf("Unexpected external links: %v", data.ExternalLinks)
+	}
+	if len(data.Images) != 1 || data.Images[0] != server.URL+"/img/a.png" {
+		t.Errorf("Image should be resolved against <base href>, got %v", data.Images)
+	}
+	if len(data.ExternalResources) != 1 || data.ExternalResources[0] != "https://cdn.example.net/style.css" {
+		t.Errorf("Unexpected external resources: %v", data.ExternalResources)
+	}
+}
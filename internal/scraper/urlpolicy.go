@@ -0,0 +1,229 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+
+	"web-scraper-api/internal/config"
+)
+
+// BlockReason is a machine-readable reason why a URL was blocked.
+type BlockReason string
+
+const (
+	BlockReasonScheme         BlockReason = "scheme_not_allowed"
+	BlockReasonHostDenied     BlockReason = "host_denied"
+	BlockReasonHostNotAllowed BlockReason = "host_not_allowed"
+	BlockReasonPrivateNetwork BlockReason = "private_network"
+)
+
+// BlockedURLError is returned when the URL safety policy refuses a URL,
+// either up front, on a redirect or when connecting to a resolved address.
+type BlockedURLError struct {
+	URL    string
+	Reason BlockReason
+	Detail string
+}
+
+func (e *BlockedURLError) Error() string {
+	return fmt.Sprintf("blocked URL %s: %s (%s)", e.URL, e.Detail, e.Reason)
+}
+
+// specialNetworks are non-public ranges not covered by the net/netip
+// helpers: shared address space, IETF protocol assignments, benchmarking,
+// documentation and reserved ranges, and NAT64 which can embed any IPv4
+// address.
+var specialNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// hostResolver looks up the addresses of a host; net.DefaultResolver
+// outside of tests.
+type hostResolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// urlPolicy decides which URLs the scraper may fetch. Hostnames are checked
+// against the allow and deny lists before each request and redirect; the
+// resolved addresses are checked when dialing so that DNS rebinding cannot
+// reach private networks.
+type urlPolicy struct {
+	schemes      map[string]bool
+	allowPrivate bool
+	allowedHosts []string
+	deniedHosts  []string
+	resolver     hostResolver
+}
+
+func newURLPolicy(cfg config.ScrapingConfig) *urlPolicy {
+	policy := &urlPolicy{
+		schemes:      make(map[string]bool),
+		allowPrivate: cfg.AllowPrivateNetworks,
+		resolver:     net.DefaultResolver,
+	}
+
+	schemes := cfg.AllowedSchemes
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+	for _, scheme := range schemes {
+		policy.schemes[strings.ToLower(strings.TrimSpace(scheme))] = true
+	}
+	for _, host := range cfg.AllowedHosts {
+		if host = normalizeHostPattern(host); host != "" {
+			policy.allowedHosts = append(policy.allowedHosts, host)
+		}
+	}
+	for _, host := range cfg.DeniedHosts {
+		if host = normalizeHostPattern(host); host != "" {
+			policy.deniedHosts = append(policy.deniedHosts, host)
+		}
+	}
+
+	return policy
+}
+
+func normalizeHostPattern(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// check validates the scheme and host of u. IP literals are checked right
+// away; hostnames are checked again once resolved, in control.
+func (p *urlPolicy) check(u *url.URL) error {
+	if !p.schemes[strings.ToLower(u.Scheme)] {
+		return &BlockedURLError{URL: u.String(), Reason: BlockReasonScheme, Detail: fmt.Sprintf("scheme %q is not allowed", u.Scheme)}
+	}
+	return p.checkHost(u)
+}
+
+// checkHost applies the host lists and, for IP literals and localhost, the
+// private network check to the host of u.
+func (p *urlPolicy) checkHost(u *url.URL) error {
+	host := normalizeHostPattern(u.Hostname())
+	if matchHostPatterns(host, p.deniedHosts) {
+		return &BlockedURLError{URL: u.String(), Reason: BlockReasonHostDenied, Detail: fmt.Sprintf("host %s is denied", host)}
+	}
+	if len(p.allowedHosts) > 0 && !matchHostPatterns(host, p.allowedHosts) {
+		return &BlockedURLError{URL: u.String(), Reason: BlockReasonHostNotAllowed, Detail: fmt.Sprintf("host %s is not allowed", host)}
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil && !p.addrAllowed(addr) {
+		return &BlockedURLError{URL: u.String(), Reason: BlockReasonPrivateNetwork, Detail: fmt.Sprintf("address %s is not public", addr)}
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		if !p.allowPrivate {
+			return &BlockedURLError{URL: u.String(), Reason: BlockReasonPrivateNetwork, Detail: fmt.Sprintf("host %s is not public", host)}
+		}
+	}
+	return nil
+}
+
+// control is installed as net.Dialer.Control and runs after DNS resolution
+// for every connection attempt, including those made for redirects.
+func (p *urlPolicy) control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !p.addrAllowed(addr) {
+		return &BlockedURLError{URL: address, Reason: BlockReasonPrivateNetwork, Detail: fmt.Sprintf("address %s is not public", addr)}
+	}
+	return nil
+}
+
+// checkResolved resolves the host of u and checks all of its addresses. It
+// is used for requests sent through a proxy, which resolves the target
+// itself, so that the check in control never sees the target's address.
+func (p *urlPolicy) checkResolved(ctx context.Context, u *url.URL) error {
+	if p.allowPrivate {
+		return nil
+	}
+
+	host := u.Hostname()
+	addrs := []netip.Addr{}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = append(addrs, addr)
+	} else {
+		resolved, err := p.resolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return err
+		}
+		addrs = resolved
+	}
+
+	for _, addr := range addrs {
+		if !p.addrAllowed(addr) {
+			return &BlockedURLError{URL: u.String(), Reason: BlockReasonPrivateNetwork, Detail: fmt.Sprintf("host %s resolves to %s, which is not public", host, addr)}
+		}
+	}
+	return nil
+}
+
+// addrAllowed reports whether connecting to addr is permitted.
+func (p *urlPolicy) addrAllowed(addr netip.Addr) bool {
+	if p.allowPrivate {
+		return true
+	}
+	return isPublicAddr(addr)
+}
+
+// isPublicAddr reports whether addr is a globally routable unicast address.
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() {
+		return false
+	}
+	for _, prefix := range specialNetworks {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchHostPatterns matches a host against patterns like "example.com",
+// which matches the host exactly, and "*.example.com", which matches its
+// subdomains.
+func matchHostPatterns(host string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// checkURL parses rawURL and applies the URL safety policy.
+func (s *Service) checkURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return &ScrapeError{Kind: ErrorKindPolicy, URL: rawURL, Err: errors.New("invalid URL")}
+	}
+	if err := s.urlPolicy.check(u); err != nil {
+		return &ScrapeError{Kind: ErrorKindPolicy, URL: rawURL, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"web-scraper-api/internal/config"
+	"web-scraper-api/internal/logger"
+)
+
+// newTestService returns a service that may fetch from httptest servers on
+// the loopback interface.
+func newTestService() *Service {
+	return NewServiceWithConfig(&config.Config{
+		Scraping: config.ScrapingConfig{
+			FollowRedirects:      true,
+			MaxRedirects:         defaultMaxRedirects,
+			AllowPrivateNetworks: true,
+		},
+	}, logger.New("error"))
+}
+
+func blockReason(err error) BlockReason {
+	var blocked *BlockedURLError
+	if errors.As(err, &blocked) {
+		return blocked.Reason
+	}
+	return ""
+}
+
+func TestURLPolicy_Check(t *testing.T) {
+	policy := newURLPolicy(config.ScrapingConfig{
+		AllowedSchemes: []string{"https"},
+		DeniedHosts:    []string{"*.internal.example.com", "evil.example.com"},
+	})
+
+	tests := []struct {
+		url    string
+		reason BlockReason
+	}{
+		{"https://example.com/", ""},
+		{"http://example.com/", BlockReasonScheme},
+		{"file:///etc/passwd", BlockReasonScheme},
+		{"https://evil.example.com/", BlockReasonHostDenied},
+		{"https://api.internal.example.com/", BlockReasonHostDenied},
+		{"https://169.254.169.254/latest/meta-data/", BlockReasonPrivateNetwork},
+		{"https://127.0.0.1:8080/", BlockReasonPrivateNetwork},
+		{"https://[::1]/", BlockReasonPrivateNetwork},
+		{"https://[::ffff:10.0.0.1]/", BlockReasonPrivateNetwork},
+		{"https://192.168.1.1/", BlockReasonPrivateNetwork},
+		{"https://100.64.0.1/", BlockReasonPrivateNetwork},
+		{"https://localhost/", BlockReasonPrivateNetwork},
+		{"https://8.8.8.8/", ""},
+	}
+
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if reason := blockReason(policy.check(u)); reason != tt.reason {
+			t.Errorf("%s: expected reason %q, got %q", tt.url, tt.reason, reason)
+		}
+	}
+
+	allowlist := newURLPolicy(config.ScrapingConfig{AllowedHosts: []string{"example.com"}})
+	u, _ := url.Parse("https://other.com/")
+	if reason := blockReason(allowlist.check(u)); reason != BlockReasonHostNotAllowed {
+		t.Errorf("Hosts outside the allowlist should be blocked, got %q", reason)
+	}
+}
+
+func TestScrape_BlocksPrivateNetworks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><title>internal</title></html>"))
+	}))
+	defer server.Close()
+
+	service := NewService(logger.New("error"))
+	_, err := service.ScrapeWebsiteWithOptions(context.Background(), server.URL, &CrawlingOptions{})
+	if blockReason(err) != BlockReasonPrivateNetwork || ErrorKindOf(err) != ErrorKindPolicy {
+		t.Errorf("Loopback URL should be blocked as policy error, got %v", err)
+	}
+
+	// A public hostname resolving to a private address is caught when
+	// dialing, which also covers DNS rebinding
+	policy := newURLPolicy(config.ScrapingConfig{})
+	if err := policy.control("tcp", "10.1.2.3:80", nil); blockReason(err) != BlockReasonPrivateNetwork {
+		t.Errorf("Dialing a private address should be blocked, got %v", err)
+	}
+	if err := policy.control("tcp", "93.184.216.34:443", nil); err != nil {
+		t.Errorf("Dialing a public address should be allowed, got %v", err)
+	}
+}
+
+func TestScrape_RevalidatesRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/to-file":
+			http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+		case "/to-denied":
+			http.Redirect(w, r, "http://metadata.google.internal/", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	service := NewServiceWithConfig(&config.Config{
+		Scraping: config.ScrapingConfig{
+			FollowRedirects:      true,
+			AllowPrivateNetworks: true,
+			DeniedHosts:          []string{"metadata.google.internal"},
+		},
+	}, logger.New("error"))
+	options := &CrawlingOptions{}
+
+	_, err := service.ScrapeWebsiteWithOptions(context.Background(), server.URL+"/to-file", options)
+	if blockReason(err) != BlockReasonScheme {
+		t.Errorf("Redirect to file: should be blocked, got %v", err)
+	}
+	_, err = service.ScrapeWebsiteWithOptions(context.Background(), server.URL+"/to-denied", options)
+	if blockReason(err) != BlockReasonHostDenied {
+		t.Errorf("Redirect to a denied host should be blocked, got %v", err)
+	}
+}
@@ -27,7 +27,7 @@ func main() {
 	logger.Info("🚀 WebCrawler API starting...")
 
 	// Initialize scraper service
-	scraperService := scraper.NewService(logger)
+	scraperService := scraper.NewServiceWithConfig(cfg, logger)
 
 	// Initialize API server
 	server := api.NewServer(cfg, scraperService, logger)
@@ -53,6 +53,7 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Fatalf("Server could not be shut down gracefully: %v", err)
 	}
+	scraperService.Close()
 
 	logger.Info("✅ Server successfully shut down")
 }